@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"iter"
 
 	"github.com/sivori/gsc-cli/internal/auth"
 	"github.com/sivori/gsc-cli/internal/config"
@@ -12,6 +13,16 @@ import (
 	"google.golang.org/api/searchconsole/v1"
 )
 
+// Querier runs Search Analytics queries. Client answers them from the
+// Search Console API; a local warehouse can answer them from disk.
+type Querier interface {
+	Query(ctx context.Context, req QueryRequest) (*QueryResult, error)
+	QueryAll(ctx context.Context, req QueryRequest) (*QueryResult, error)
+	QueryAllWithProgress(ctx context.Context, req QueryRequest, progress ProgressFunc) (*QueryResult, error)
+	Rows(ctx context.Context, req QueryRequest, progress ProgressFunc) iter.Seq2[QueryRow, error]
+	ProbeFreshness(ctx context.Context, searchType string, includeFresh bool) (*Freshness, error)
+}
+
 // Client wraps the Google Search Console API client
 type Client struct {
 	service *searchconsole.Service
@@ -19,48 +30,78 @@ type Client struct {
 }
 
 // NewClientForSites creates a client for listing sites (no site URL required)
-func NewClientForSites() (*Client, error) {
-	return NewClient("")
+func NewClientForSites(ctx context.Context) (*Client, error) {
+	return NewClient(ctx, "")
+}
+
+// NewClient creates a new Search Console API client with read-only access
+func NewClient(ctx context.Context, siteURL string) (*Client, error) {
+	return NewClientWithScope(ctx, siteURL, auth.ScopeReadOnly)
 }
 
-// NewClient creates a new Search Console API client
-func NewClient(siteURL string) (*Client, error) {
+// NewClientWithScope creates a client whose credentials carry scope, for
+// commands that need more than read-only access. If the stored OAuth login
+// was not granted it, the user is asked to approve it.
+func NewClientWithScope(ctx context.Context, siteURL, scope string) (*Client, error) {
+	ts, err := tokenSource(ctx, scope)
+	if err != nil {
+		return nil, classifyError(siteURL, err)
+	}
+
+	return newClient(ctx, siteURL, ts)
+}
+
+// NewClientFromToken creates a client using a provided token (for testing)
+func NewClientFromToken(ctx context.Context, siteURL string, token *oauth2.Token, oauthConfig *oauth2.Config) (*Client, error) {
+	return newClient(ctx, siteURL, oauthConfig.TokenSource(ctx, token))
+}
+
+// grantScopes asks for additional scopes; tests replace it
+var grantScopes = auth.GrantScopes
+
+// tokenSource picks the credentials to use, as described by auth.ActiveSource.
+// If the stored OAuth login was not granted scope, the user is asked to
+// approve it in the flow they logged in with.
+func tokenSource(ctx context.Context, scope string) (oauth2.TokenSource, error) {
+	switch auth.ActiveSource() {
+	case auth.SourceAccessToken:
+		return auth.AccessTokenSource(), nil
+	case auth.SourceServiceAccount:
+		return auth.ServiceAccountTokenSource(ctx, config.GetServiceAccountKey(), config.GetServiceAccountSubject(), scope)
+	case auth.SourceADC:
+		return auth.ADCTokenSource(ctx, scope)
+	}
+
 	clientSecretPath := config.GetClientSecretPath()
 	if clientSecretPath == "" {
 		return nil, fmt.Errorf("not configured - run 'gsc auth login' first")
 	}
 
-	token, err := auth.GetValidToken(clientSecretPath)
+	token, err := auth.GetValidToken(config.Profile(), clientSecretPath)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx := context.Background()
+	// Incremental consent: ask for the scope only when a command needs it
+	if !auth.HasScopes(auth.ProfileScopes(), scope) {
+		if token, err = grantScopes(ctx, clientSecretPath, config.GetNoBrowser(), scope); err != nil {
+			return nil, err
+		}
+	}
 
-	// Create HTTP client with OAuth2 token
 	oauthConfig, err := auth.LoadClientConfig(clientSecretPath)
 	if err != nil {
 		return nil, err
 	}
 
-	httpClient := oauthConfig.Client(ctx, token)
-
-	// Create Search Console service
-	service, err := searchconsole.NewService(ctx, option.WithHTTPClient(httpClient))
-	if err != nil {
-		return nil, fmt.Errorf("could not create Search Console service: %w", err)
-	}
-
-	return &Client{
-		service: service,
-		siteURL: siteURL,
-	}, nil
+	return oauthConfig.TokenSource(ctx, token), nil
 }
 
-// NewClientFromToken creates a client using a provided token (for testing)
-func NewClientFromToken(siteURL string, token *oauth2.Token, oauthConfig *oauth2.Config) (*Client, error) {
-	ctx := context.Background()
-	httpClient := oauthConfig.Client(ctx, token)
+// newClient creates a client whose requests are authorized by ts, rate-limited
+// and retried on transient failures
+func newClient(ctx context.Context, siteURL string, ts oauth2.TokenSource) (*Client, error) {
+	httpClient := oauth2.NewClient(ctx, ts)
+	httpClient.Transport = &retryTransport{base: httpClient.Transport}
 
 	service, err := searchconsole.NewService(ctx, option.WithHTTPClient(httpClient))
 	if err != nil {
@@ -74,10 +115,10 @@ func NewClientFromToken(siteURL string, token *oauth2.Token, oauthConfig *oauth2
 }
 
 // ListSites returns all sites the user has access to
-func (c *Client) ListSites() ([]*searchconsole.WmxSite, error) {
-	resp, err := c.service.Sites.List().Do()
+func (c *Client) ListSites(ctx context.Context) ([]*searchconsole.WmxSite, error) {
+	resp, err := c.service.Sites.List().Context(ctx).Do()
 	if err != nil {
-		return nil, fmt.Errorf("could not list sites: %w", err)
+		return nil, fmt.Errorf("could not list sites: %w", classifyError("", err))
 	}
 	return resp.SiteEntry, nil
 }
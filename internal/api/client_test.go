@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sivori/gsc-cli/internal/auth"
+	"github.com/sivori/gsc-cli/internal/config"
+
+	"golang.org/x/oauth2"
+)
+
+// setupOAuthProfile configures an OAuth login with a token in GSC_TOKEN
+// and no recorded scopes, i.e. read-only access
+func setupOAuthProfile(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	secret := filepath.Join(dir, "client_secret.json")
+	if err := os.WriteFile(secret, []byte(`{"installed":{"client_id":"id","client_secret":"secret",
+		"auth_uri":"https://accounts.google.com/o/oauth2/auth","token_uri":"https://oauth2.googleapis.com/token",
+		"redirect_uris":["http://localhost"]}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"GSC_ACCESS_TOKEN", "GSC_SERVICE_ACCOUNT_KEY", "GSC_USE_ADC", "GSC_TOKEN_STORE", "GSC_PROFILE", "GOOGLE_APPLICATION_CREDENTIALS"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("GSC_CLIENT_SECRET_PATH", secret)
+	t.Setenv("GSC_TOKEN", `{"access_token":"a","token_type":"Bearer","refresh_token":"r","expiry":"2100-01-01T00:00:00Z"}`)
+
+	if err := config.Init(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenSourceAsksForMissingScopes(t *testing.T) {
+	setupOAuthProfile(t)
+
+	var granted [][]string
+	grantScopes = func(ctx context.Context, clientSecretPath string, noBrowser bool, needed ...string) (*oauth2.Token, error) {
+		granted = append(granted, needed)
+		return &oauth2.Token{AccessToken: "b"}, nil
+	}
+	t.Cleanup(func() { grantScopes = auth.GrantScopes })
+
+	ctx := context.Background()
+	if _, err := tokenSource(ctx, auth.ScopeReadOnly); err != nil {
+		t.Fatal(err)
+	}
+	if len(granted) != 0 {
+		t.Fatalf("read-only access asked for consent: %v", granted)
+	}
+
+	if _, err := tokenSource(ctx, auth.ScopeReadWrite); err != nil {
+		t.Fatal(err)
+	}
+	if len(granted) != 1 || !slices.Equal(granted[0], []string{auth.ScopeReadWrite}) {
+		t.Fatalf("consent requests = %v, want one for %s", granted, auth.ScopeReadWrite)
+	}
+
+	// Once the scope is recorded, no more consent is needed
+	if err := config.SetScopes([]string{auth.ScopeReadWrite}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokenSource(ctx, auth.ScopeReadWrite); err != nil {
+		t.Fatal(err)
+	}
+	if len(granted) != 1 {
+		t.Errorf("consent asked again after the scope was granted: %v", granted)
+	}
+}
@@ -0,0 +1,395 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	dataDelay  = 3 // GSC data is typically 3 days behind when not probed
+)
+
+// Dates resolves default ranges, comparison periods and date expressions
+// relative to the latest day with data. The zero value assumes data runs
+// to a fixed delay before today.
+type Dates struct {
+	latest time.Time // last day with data; zero uses the fixed delay
+	now    time.Time // zero means time.Now, set by tests
+}
+
+// NewDates returns a Dates anchored on latest, the last day with data as
+// YYYY-MM-DD. An empty latest uses the fixed delay.
+func NewDates(latest string) (Dates, error) {
+	if latest == "" {
+		return Dates{}, nil
+	}
+	d, err := time.Parse(dateLayout, latest)
+	if err != nil {
+		return Dates{}, fmt.Errorf("invalid latest data date: %s", latest)
+	}
+	return Dates{latest: d}, nil
+}
+
+// clock returns the current time
+func (d Dates) clock() time.Time {
+	if d.now.IsZero() {
+		return time.Now()
+	}
+	return d.now
+}
+
+// dataEnd returns the last day with data, as a date at midnight UTC
+func (d Dates) dataEnd() time.Time {
+	if !d.latest.IsZero() {
+		return d.latest
+	}
+	end := d.clock().AddDate(0, 0, -dataDelay)
+	return time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// DefaultRange returns the default date range (last 28 days)
+func (d Dates) DefaultRange() (start, end string) {
+	return d.RangeForDays(28)
+}
+
+// RangeForDays returns a date range for the last N days with data
+func (d Dates) RangeForDays(days int) (start, end string) {
+	last := d.dataEnd()
+	end = last.Format(dateLayout)
+	start = last.AddDate(0, 0, -(days - 1)).Format(dateLayout)
+	return
+}
+
+// Comparison periods
+const (
+	PeriodWeek            = "week"             // rolling 7 days
+	PeriodMonth           = "month"            // rolling 30 days
+	PeriodQuarter         = "quarter"          // rolling 90 days
+	PeriodCalendarMonth   = "calendar-month"   // last complete calendar month
+	PeriodCalendarQuarter = "calendar-quarter" // last complete calendar quarter
+)
+
+// Periods lists every valid comparison period
+var Periods = []string{PeriodWeek, PeriodMonth, PeriodQuarter, PeriodCalendarMonth, PeriodCalendarQuarter}
+
+// What the current period is compared against
+const (
+	AgainstPrevious    = "previous"     // the preceding period (same length, or the previous calendar month/quarter)
+	AgainstYear        = "year"         // the same calendar dates one year earlier
+	AgainstYearWeekday = "year-weekday" // 52 weeks earlier, so weekdays line up
+)
+
+// Againsts lists every valid comparison baseline
+var Againsts = []string{AgainstPrevious, AgainstYear, AgainstYearWeekday}
+
+// ComparisonPeriod represents two date ranges for comparison
+type ComparisonPeriod struct {
+	CurrentStart  string
+	CurrentEnd    string
+	PreviousStart string
+	PreviousEnd   string
+}
+
+// ComparisonPeriod returns the current date range for the period and the
+// range it is compared against
+func (d Dates) ComparisonPeriod(period, against string) (ComparisonPeriod, error) {
+	end := d.dataEnd()
+
+	var start time.Time
+	switch period {
+	case PeriodWeek:
+		start = end.AddDate(0, 0, -6)
+	case PeriodMonth:
+		start = end.AddDate(0, 0, -29)
+	case PeriodQuarter:
+		start = end.AddDate(0, 0, -89)
+	case PeriodCalendarMonth:
+		start, end = lastCompleteMonth(end)
+		if against == AgainstPrevious || against == "" {
+			prevStart := start.AddDate(0, -1, 0)
+			return newComparisonPeriod(start, end, prevStart, start.AddDate(0, 0, -1)), nil
+		}
+	case PeriodCalendarQuarter:
+		start, end = lastCompleteQuarter(end)
+		if against == AgainstPrevious || against == "" {
+			prevStart := start.AddDate(0, -3, 0)
+			return newComparisonPeriod(start, end, prevStart, start.AddDate(0, 0, -1)), nil
+		}
+	default:
+		return ComparisonPeriod{}, fmt.Errorf("invalid period: %s (valid: %s)", period, strings.Join(Periods, ", "))
+	}
+
+	return comparePeriods(start, end, against)
+}
+
+// ComparePeriod returns the range that an arbitrary start/end range is compared against
+func ComparePeriod(start, end, against string) (ComparisonPeriod, error) {
+	s, err := time.Parse(dateLayout, start)
+	if err != nil {
+		return ComparisonPeriod{}, fmt.Errorf("invalid start date: %s (expected YYYY-MM-DD)", start)
+	}
+	e, err := time.Parse(dateLayout, end)
+	if err != nil {
+		return ComparisonPeriod{}, fmt.Errorf("invalid end date: %s (expected YYYY-MM-DD)", end)
+	}
+	if e.Before(s) {
+		return ComparisonPeriod{}, fmt.Errorf("end date %s is before start date %s", end, start)
+	}
+	return comparePeriods(s, e, against)
+}
+
+func comparePeriods(start, end time.Time, against string) (ComparisonPeriod, error) {
+	switch against {
+	case AgainstPrevious, "":
+		days := int(end.Sub(start).Hours()/24) + 1
+		prevEnd := start.AddDate(0, 0, -1)
+		return newComparisonPeriod(start, end, prevEnd.AddDate(0, 0, -(days-1)), prevEnd), nil
+	case AgainstYear:
+		return newComparisonPeriod(start, end, start.AddDate(-1, 0, 0), end.AddDate(-1, 0, 0)), nil
+	case AgainstYearWeekday:
+		return newComparisonPeriod(start, end, start.AddDate(0, 0, -364), end.AddDate(0, 0, -364)), nil
+	}
+	return ComparisonPeriod{}, fmt.Errorf("invalid comparison: %s (valid: %s)", against, strings.Join(Againsts, ", "))
+}
+
+func newComparisonPeriod(currentStart, currentEnd, previousStart, previousEnd time.Time) ComparisonPeriod {
+	return ComparisonPeriod{
+		CurrentStart:  currentStart.Format(dateLayout),
+		CurrentEnd:    currentEnd.Format(dateLayout),
+		PreviousStart: previousStart.Format(dateLayout),
+		PreviousEnd:   previousEnd.Format(dateLayout),
+	}
+}
+
+// lastCompleteMonth returns the last calendar month ending on or before end
+func lastCompleteMonth(end time.Time) (time.Time, time.Time) {
+	monthStart := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := monthStart.AddDate(0, 1, -1)
+	if monthEnd.After(end) {
+		monthStart = monthStart.AddDate(0, -1, 0)
+		monthEnd = monthStart.AddDate(0, 1, -1)
+	}
+	return monthStart, monthEnd
+}
+
+// lastCompleteQuarter returns the last calendar quarter ending on or before end
+func lastCompleteQuarter(end time.Time) (time.Time, time.Time) {
+	firstMonth := time.Month((int(end.Month())-1)/3*3 + 1)
+	quarterStart := time.Date(end.Year(), firstMonth, 1, 0, 0, 0, 0, time.UTC)
+	quarterEnd := quarterStart.AddDate(0, 3, -1)
+	if quarterEnd.After(end) {
+		quarterStart = quarterStart.AddDate(0, -3, 0)
+		quarterEnd = quarterStart.AddDate(0, 3, -1)
+	}
+	return quarterStart, quarterEnd
+}
+
+// DateRange is a resolved, inclusive date range
+type DateRange struct {
+	Start string
+	End   string
+}
+
+// dateExprHelp lists the accepted date expressions for error messages
+const dateExprHelp = "YYYY-MM-DD, today, yesterday, today-Nd/w/m/y, last-Nd, wtd, mtd, qtd, ytd, " +
+	"last-week, last-month, last-quarter, last-year"
+
+// ParseRange resolves --start/--end values into a concrete date range.
+//
+// Each value is either a date (2025-01-31, today, yesterday, today-3d,
+// today-2w) or a range expression (last-28d, wtd, mtd, qtd, ytd, last-week,
+// last-month, last-quarter, last-year; "previous-" works like "last-").
+// A range expression as start contributes its first day and, when end is
+// empty, its last day. A lone start date runs to the latest data date.
+func (d Dates) ParseRange(start, end string) (DateRange, error) {
+	if start == "" {
+		return DateRange{}, fmt.Errorf("a start date is required when an end date is given")
+	}
+
+	now := d.clock()
+	latest := d.dataEnd()
+	startDate, endDate, isRange, err := d.parseExpr(start)
+	if err != nil {
+		return DateRange{}, err
+	}
+
+	switch {
+	case end != "":
+		// A range expression as end contributes its last day
+		if _, endDate, _, err = d.parseExpr(end); err != nil {
+			return DateRange{}, err
+		}
+	case !isRange:
+		// A lone start inside the reporting delay has nothing to run to
+		if startDate.After(latest) {
+			day := startDate.Format(dateLayout)
+			if s := strings.ToLower(strings.TrimSpace(start)); s != day {
+				day = s + " (" + day + ")"
+			}
+			return DateRange{}, fmt.Errorf("no data yet for %s; latest is %s", day, latest.Format(dateLayout))
+		}
+		endDate = latest
+	case endDate.After(latest):
+		// Clamp relative ranges to the last day with data
+		endDate = latest
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if endDate.After(today) {
+		return DateRange{}, fmt.Errorf("end date %s is in the future", endDate.Format(dateLayout))
+	}
+	if endDate.Before(startDate) {
+		return DateRange{}, fmt.Errorf("end date %s is before start date %s", endDate.Format(dateLayout), startDate.Format(dateLayout))
+	}
+
+	return DateRange{Start: startDate.Format(dateLayout), End: endDate.Format(dateLayout)}, nil
+}
+
+// NeedsDataDate reports whether resolving start and end depends on the latest
+// data date: the default range, a start without an end, or a range expression.
+// Two plain dates do not.
+func NeedsDataDate(start, end string) bool {
+	if start == "" || end == "" {
+		return true
+	}
+	for _, expr := range []string{start, end} {
+		if _, _, isRange, err := (Dates{}).parseExpr(expr); err != nil || isRange {
+			return true
+		}
+	}
+	return false
+}
+
+// parseExpr resolves an expression to the first and last day it covers.
+// Single dates return the same day twice and isRange false.
+func (d Dates) parseExpr(expr string) (first, last time.Time, isRange bool, err error) {
+	s := strings.ToLower(strings.TrimSpace(expr))
+	now := d.clock()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	latest := d.dataEnd()
+
+	invalid := func() (time.Time, time.Time, bool, error) {
+		return time.Time{}, time.Time{}, false, fmt.Errorf("invalid date %q (expected %s)", expr, dateExprHelp)
+	}
+
+	if strings.HasPrefix(s, "previous-") {
+		s = "last-" + strings.TrimPrefix(s, "previous-")
+	}
+
+	switch s {
+	case "today":
+		return today, today, false, nil
+	case "yesterday":
+		d := today.AddDate(0, 0, -1)
+		return d, d, false, nil
+	// Periods to date run up to the latest data date, so they start in the
+	// period holding that date rather than today
+	case "wtd":
+		// Weeks start on Monday
+		offset := (int(latest.Weekday()) + 6) % 7
+		return latest.AddDate(0, 0, -offset), latest, true, nil
+	case "mtd":
+		return time.Date(latest.Year(), latest.Month(), 1, 0, 0, 0, 0, time.UTC), latest, true, nil
+	case "qtd":
+		firstMonth := time.Month((int(latest.Month())-1)/3*3 + 1)
+		return time.Date(latest.Year(), firstMonth, 1, 0, 0, 0, 0, time.UTC), latest, true, nil
+	case "ytd":
+		return time.Date(latest.Year(), 1, 1, 0, 0, 0, 0, time.UTC), latest, true, nil
+	case "last-week":
+		offset := (int(today.Weekday()) + 6) % 7
+		weekStart := today.AddDate(0, 0, -offset-7)
+		return weekStart, weekStart.AddDate(0, 0, 6), true, nil
+	case "last-month":
+		monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+		return monthStart, monthStart.AddDate(0, 1, -1), true, nil
+	case "last-quarter":
+		firstMonth := time.Month((int(today.Month())-1)/3*3 + 1)
+		quarterStart := time.Date(today.Year(), firstMonth, 1, 0, 0, 0, 0, time.UTC).AddDate(0, -3, 0)
+		return quarterStart, quarterStart.AddDate(0, 3, -1), true, nil
+	case "last-year":
+		yearStart := time.Date(today.Year()-1, 1, 1, 0, 0, 0, 0, time.UTC)
+		return yearStart, yearStart.AddDate(1, 0, -1), true, nil
+	}
+
+	// last-Nd: the N days ending on the latest data date
+	if rest, ok := strings.CutPrefix(s, "last-"); ok {
+		n, unit, ok := parseOffset(rest)
+		if !ok || unit != 'd' || n < 1 {
+			return invalid()
+		}
+		return latest.AddDate(0, 0, -(n - 1)), latest, true, nil
+	}
+
+	// today-Nd, today-Nw, today-Nm, today-Ny
+	if rest, ok := strings.CutPrefix(s, "today-"); ok {
+		n, unit, ok := parseOffset(rest)
+		if !ok {
+			return invalid()
+		}
+		var day time.Time
+		switch unit {
+		case 'd':
+			day = today.AddDate(0, 0, -n)
+		case 'w':
+			day = today.AddDate(0, 0, -7*n)
+		case 'm':
+			day = today.AddDate(0, -n, 0)
+		case 'y':
+			day = today.AddDate(-n, 0, 0)
+		}
+		return day, day, false, nil
+	}
+
+	day, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return invalid()
+	}
+	return day, day, false, nil
+}
+
+// parseOffset parses "3d", "2w", "6m" or "1y" into a count and unit
+func parseOffset(s string) (int, byte, bool) {
+	if len(s) < 2 {
+		return 0, 0, false
+	}
+	unit := s[len(s)-1]
+	if unit != 'd' && unit != 'w' && unit != 'm' && unit != 'y' {
+		return 0, 0, false
+	}
+	n := 0
+	for _, c := range s[:len(s)-1] {
+		if c < '0' || c > '9' {
+			return 0, 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, unit, true
+}
+
+// retentionMonths is how long Search Console keeps performance data
+const retentionMonths = 16
+
+// EarliestDataDate returns the oldest day Search Console still retains
+func (d Dates) EarliestDataDate() string {
+	return d.dataEnd().AddDate(0, -retentionMonths, 0).Format(dateLayout)
+}
+
+// DaysBetween returns every day from start to end inclusive
+func DaysBetween(start, end string) ([]string, error) {
+	s, err := time.Parse(dateLayout, start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date: %s (expected YYYY-MM-DD)", start)
+	}
+	e, err := time.Parse(dateLayout, end)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date: %s (expected YYYY-MM-DD)", end)
+	}
+
+	var days []string
+	for d := s; !d.After(e); d = d.AddDate(0, 0, 1) {
+		days = append(days, d.Format(dateLayout))
+	}
+	return days, nil
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateRangePeriodsToDate(t *testing.T) {
+	tests := []struct {
+		name       string
+		now        string
+		latest     string // probed latest data date, or "" for the fixed delay
+		expr       string
+		start, end string
+		err        string // expected error, if any
+	}{
+		{"mtd early in month", "2026-10-02", "2026-09-29", "mtd", "2026-09-01", "2026-09-29", ""},
+		{"mtd on last data day of month", "2026-10-03", "2026-09-30", "mtd", "2026-09-01", "2026-09-30", ""},
+		{"mtd on first data day of month", "2026-10-04", "2026-10-01", "mtd", "2026-10-01", "2026-10-01", ""},
+		{"mtd fixed delay", "2026-10-02", "", "mtd", "2026-09-01", "2026-09-29", ""},
+		{"qtd across quarter start", "2026-10-02", "2026-09-29", "qtd", "2026-07-01", "2026-09-29", ""},
+		{"qtd mid quarter", "2026-11-15", "2026-11-12", "qtd", "2026-10-01", "2026-11-12", ""},
+		{"ytd across new year", "2027-01-01", "2026-12-29", "ytd", "2026-01-01", "2026-12-29", ""},
+		{"ytd on first data day of year", "2027-01-04", "2027-01-01", "ytd", "2027-01-01", "2027-01-01", ""},
+		{"wtd across week start", "2026-10-13", "2026-10-11", "wtd", "2026-10-05", "2026-10-11", ""},
+		{"wtd on a monday", "2026-10-15", "2026-10-12", "wtd", "2026-10-12", "2026-10-12", ""},
+		{"last-7d", "2026-10-02", "2026-09-29", "last-7d", "2026-09-23", "2026-09-29", ""},
+		{"last-month clamped", "2026-10-02", "2026-09-29", "last-month", "2026-09-01", "2026-09-29", ""},
+		{"start on latest", "2026-10-02", "2026-09-29", "2026-09-29", "2026-09-29", "2026-09-29", ""},
+		{"start today", "2026-10-16", "2026-10-13", "today", "", "", "no data yet for today (2026-10-16); latest is 2026-10-13"},
+		{"start in delay window", "2026-10-16", "", "yesterday", "", "", "no data yet for yesterday (2026-10-15); latest is 2026-10-13"},
+		{"start date after latest", "2026-10-16", "2026-10-13", "2026-10-14", "", "", "no data yet for 2026-10-14; latest is 2026-10-13"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dates, err := NewDates(tt.latest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now, err := time.Parse(dateLayout, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dates.now = now.Add(10 * time.Hour)
+
+			got, err := dates.ParseRange(tt.expr, "")
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("ParseRange(%q) error = %v, want %q", tt.expr, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRange(%q) error: %v", tt.expr, err)
+			}
+			if got.Start != tt.start || got.End != tt.end {
+				t.Errorf("ParseRange(%q) = %s..%s, want %s..%s", tt.expr, got.Start, got.End, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestNeedsDataDate(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want       bool
+	}{
+		{"", "", true},
+		{"2026-09-01", "", true},
+		{"2026-09-01", "2026-09-30", false},
+		{"2026-09-01", "yesterday", false},
+		{"today-7d", "today-1d", false},
+		{"mtd", "", true},
+		{"2026-09-01", "last-7d", true},
+		{"last-month", "2026-09-30", true},
+	}
+
+	for _, tt := range tests {
+		if got := NeedsDataDate(tt.start, tt.end); got != tt.want {
+			t.Errorf("NeedsDataDate(%q, %q) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestDatesAnchoredOnLatest(t *testing.T) {
+	dates, err := NewDates("2026-09-29")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if start, end := dates.RangeForDays(7); start != "2026-09-23" || end != "2026-09-29" {
+		t.Errorf("RangeForDays(7) = %s..%s, want 2026-09-23..2026-09-29", start, end)
+	}
+	if start, end := dates.DefaultRange(); start != "2026-09-02" || end != "2026-09-29" {
+		t.Errorf("DefaultRange() = %s..%s, want 2026-09-02..2026-09-29", start, end)
+	}
+	p, err := dates.ComparisonPeriod(PeriodCalendarMonth, AgainstPrevious)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.CurrentStart != "2026-08-01" || p.CurrentEnd != "2026-08-31" || p.PreviousStart != "2026-07-01" {
+		t.Errorf("ComparisonPeriod(calendar-month) = %+v, want August against July", p)
+	}
+	if got := dates.EarliestDataDate(); got != "2025-05-29" {
+		t.Errorf("EarliestDataDate() = %s, want 2025-05-29", got)
+	}
+
+	if _, err := NewDates("29/09/2026"); err == nil {
+		t.Error("NewDates accepted an invalid date")
+	}
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/searchconsole/v1"
+)
+
+// QuotaError reports that a Search Console quota is exhausted and retrying
+// did not help
+type QuotaError struct {
+	Err error
+}
+
+func (e *QuotaError) Error() string {
+	return "Search Console quota exceeded: " + apiMessage(e.Err)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return e.Err
+}
+
+// PermissionError reports that the account's permission level does not
+// allow the request
+type PermissionError struct {
+	SiteURL string
+	Level   string // permission level on the site, if known
+	Err     error
+}
+
+func (e *PermissionError) Error() string {
+	if e.SiteURL == "" {
+		return "permission denied: " + apiMessage(e.Err)
+	}
+	return "permission denied for " + e.SiteURL + ": " + apiMessage(e.Err)
+}
+
+func (e *PermissionError) Unwrap() error {
+	return e.Err
+}
+
+// SiteNotFoundError reports that a site is not one of the account's
+// properties, usually because the URL is in the wrong format
+type SiteNotFoundError struct {
+	SiteURL    string
+	Suggestion string // closest matching property, if any
+	Err        error
+}
+
+func (e *SiteNotFoundError) Error() string {
+	return e.SiteURL + " is not one of your Search Console properties"
+}
+
+func (e *SiteNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// NotVerifiedError reports that the account has not verified a site
+type NotVerifiedError struct {
+	SiteURL string
+	Err     error
+}
+
+func (e *NotVerifiedError) Error() string {
+	return "you are not a verified user of " + e.SiteURL
+}
+
+func (e *NotVerifiedError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidRequestError reports a request the API rejected, such as an
+// invalid dimension, filter or search type combination
+type InvalidRequestError struct {
+	Err error
+}
+
+func (e *InvalidRequestError) Error() string {
+	return "invalid request: " + apiMessage(e.Err)
+}
+
+func (e *InvalidRequestError) Unwrap() error {
+	return e.Err
+}
+
+// TokenRevokedError reports that the stored token was revoked or has expired
+// for good and can no longer be refreshed
+type TokenRevokedError struct {
+	Err error
+}
+
+func (e *TokenRevokedError) Error() string {
+	return "authorization revoked or expired"
+}
+
+func (e *TokenRevokedError) Unwrap() error {
+	return e.Err
+}
+
+// quotaReasons are the error reasons Google APIs use for quota exhaustion
+var quotaReasons = map[string]bool{
+	"rateLimitExceeded":     true,
+	"userRateLimitExceeded": true,
+	"quotaExceeded":         true,
+	"dailyLimitExceeded":    true,
+}
+
+// classifyError wraps Search Console API and token errors in a typed error
+func classifyError(siteURL string, err error) error {
+	var rerr *oauth2.RetrieveError
+	if errors.As(err, &rerr) && rerr.ErrorCode == "invalid_grant" {
+		return &TokenRevokedError{Err: err}
+	}
+
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return err
+	}
+
+	if gerr.Code == http.StatusTooManyRequests {
+		return &QuotaError{Err: err}
+	}
+	for _, item := range gerr.Errors {
+		if quotaReasons[item.Reason] {
+			return &QuotaError{Err: err}
+		}
+	}
+
+	switch gerr.Code {
+	case http.StatusBadRequest:
+		return &InvalidRequestError{Err: err}
+	case http.StatusUnauthorized:
+		return &TokenRevokedError{Err: err}
+	case http.StatusForbidden, http.StatusNotFound:
+		return &PermissionError{SiteURL: siteURL, Err: err}
+	}
+
+	return err
+}
+
+// classifySiteError classifies an error from a request for the client's
+// site. A permission error is narrowed down by looking the site up in the
+// account's properties.
+func (c *Client) classifySiteError(ctx context.Context, err error) error {
+	err = classifyError(c.siteURL, err)
+
+	var perr *PermissionError
+	if c.siteURL == "" || !errors.As(err, &perr) {
+		return err
+	}
+
+	sites, listErr := c.ListSites(ctx)
+	if listErr != nil {
+		return err
+	}
+
+	for _, site := range sites {
+		if site.SiteUrl != c.siteURL {
+			continue
+		}
+		if site.PermissionLevel == "siteUnverifiedUser" {
+			return &NotVerifiedError{SiteURL: c.siteURL, Err: perr.Err}
+		}
+		perr.Level = site.PermissionLevel
+		return perr
+	}
+
+	return &SiteNotFoundError{
+		SiteURL:    c.siteURL,
+		Suggestion: ClosestSite(c.siteURL, sites),
+		Err:        perr.Err,
+	}
+}
+
+// ClosestSite returns the property that most likely matches siteURL, or ""
+// if none is close
+func ClosestSite(siteURL string, sites []*searchconsole.WmxSite) string {
+	want := normalizeSite(siteURL)
+
+	best, bestDist := "", -1
+	for _, site := range sites {
+		got := normalizeSite(site.SiteUrl)
+		if got == want {
+			// Same host in another format, e.g. a missing trailing slash
+			// or a domain property instead of a URL prefix
+			return site.SiteUrl
+		}
+		if d := editDistance(want, got); bestDist < 0 || d < bestDist {
+			best, bestDist = site.SiteUrl, d
+		}
+	}
+
+	if bestDist < 0 || bestDist > len(want)/3 {
+		return ""
+	}
+	return best
+}
+
+// normalizeSite reduces a site URL to its bare host and path
+func normalizeSite(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, prefix := range []string{"sc-domain:", "https://", "http://", "www."} {
+		s = strings.TrimPrefix(s, prefix)
+	}
+	return strings.TrimSuffix(s, "/")
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// apiMessage returns the message of a googleapi error without its details
+func apiMessage(err error) string {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) && gerr.Message != "" {
+		return gerr.Message
+	}
+	return err.Error()
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/searchconsole/v1"
+)
+
+func TestClassifyError(t *testing.T) {
+	apiErr := func(code int, reason string) error {
+		gerr := &googleapi.Error{Code: code, Message: "message"}
+		if reason != "" {
+			gerr.Errors = []googleapi.ErrorItem{{Reason: reason}}
+		}
+		// The client wraps API errors before they are classified
+		return fmt.Errorf("could not query: %w", gerr)
+	}
+	revoked := &oauth2.RetrieveError{ErrorCode: "invalid_grant"}
+	plain := errors.New("connection reset")
+
+	tests := []struct {
+		name string
+		err  error
+		want interface{}
+	}{
+		{"forbidden", apiErr(http.StatusForbidden, "forbidden"), &PermissionError{}},
+		{"not found", apiErr(http.StatusNotFound, "notFound"), &PermissionError{}},
+		{"bad request", apiErr(http.StatusBadRequest, "badRequest"), &InvalidRequestError{}},
+		{"unauthorized", apiErr(http.StatusUnauthorized, "authError"), &TokenRevokedError{}},
+		{"invalid grant", fmt.Errorf("could not refresh: %w", revoked), &TokenRevokedError{}},
+		{"too many requests", apiErr(http.StatusTooManyRequests, ""), &QuotaError{}},
+		{"rate limit", apiErr(http.StatusForbidden, "rateLimitExceeded"), &QuotaError{}},
+		{"user rate limit", apiErr(http.StatusForbidden, "userRateLimitExceeded"), &QuotaError{}},
+		{"daily limit", apiErr(http.StatusForbidden, "dailyLimitExceeded"), &QuotaError{}},
+		{"quota", apiErr(http.StatusForbidden, "quotaExceeded"), &QuotaError{}},
+		{"server error", apiErr(http.StatusInternalServerError, "backendError"), nil},
+		{"other refresh error", &oauth2.RetrieveError{ErrorCode: "invalid_client"}, nil},
+		{"not an API error", plain, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyError("sc-domain:example.com", tt.err)
+			if !errors.Is(got, tt.err) {
+				t.Errorf("classified error %v does not wrap the original", got)
+			}
+
+			switch tt.want.(type) {
+			case *PermissionError:
+				var perr *PermissionError
+				if !errors.As(got, &perr) {
+					t.Fatalf("got %T, want *PermissionError", got)
+				}
+				if perr.SiteURL != "sc-domain:example.com" {
+					t.Errorf("SiteURL = %q, want the site", perr.SiteURL)
+				}
+			case *InvalidRequestError:
+				var ierr *InvalidRequestError
+				if !errors.As(got, &ierr) {
+					t.Errorf("got %T, want *InvalidRequestError", got)
+				}
+			case *TokenRevokedError:
+				var rerr *TokenRevokedError
+				if !errors.As(got, &rerr) {
+					t.Errorf("got %T, want *TokenRevokedError", got)
+				}
+			case *QuotaError:
+				var qerr *QuotaError
+				if !errors.As(got, &qerr) {
+					t.Errorf("got %T, want *QuotaError", got)
+				}
+			default:
+				if got != tt.err {
+					t.Errorf("got %T, want the error unchanged", got)
+				}
+			}
+		})
+	}
+}
+
+func TestClassifiedErrorMessages(t *testing.T) {
+	gerr := &googleapi.Error{Code: http.StatusForbidden, Message: "User does not have sufficient permission"}
+	if got, want := classifyError("https://example.com/", gerr).Error(), "permission denied for https://example.com/: User does not have sufficient permission"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestClosestSite(t *testing.T) {
+	sites := []*searchconsole.WmxSite{
+		{SiteUrl: "https://www.example.com/"},
+		{SiteUrl: "sc-domain:example.org"},
+		{SiteUrl: "https://shop.example.net/"},
+	}
+
+	tests := []struct {
+		name string
+		site string
+		want string
+	}{
+		{"missing trailing slash", "https://www.example.com", "https://www.example.com/"},
+		{"http scheme", "http://www.example.com/", "https://www.example.com/"},
+		{"missing www", "https://example.com/", "https://www.example.com/"},
+		{"domain for a URL prefix", "sc-domain:example.com", "https://www.example.com/"},
+		{"URL prefix for a domain", "https://example.org/", "sc-domain:example.org"},
+		{"bare host", "example.org", "sc-domain:example.org"},
+		{"case", "HTTPS://Shop.Example.net", "https://shop.example.net/"},
+		{"typo", "sc-domain:exmaple.org", "sc-domain:example.org"},
+		{"unrelated", "sc-domain:another-site.io", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ClosestSite(tt.site, sites); got != tt.want {
+			t.Errorf("%s: ClosestSite(%q) = %q, want %q", tt.name, tt.site, got, tt.want)
+		}
+	}
+
+	if got := ClosestSite("sc-domain:example.com", nil); got != "" {
+		t.Errorf("ClosestSite() with no sites = %q, want none", got)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"example.com", "example.com", 0},
+		{"exmaple.com", "example.com", 2},
+		{"example.com", "example.org", 3},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Filter operators understood by the Search Analytics API
+const (
+	OperatorEquals         = "equals"
+	OperatorNotEquals      = "notEquals"
+	OperatorContains       = "contains"
+	OperatorNotContains    = "notContains"
+	OperatorIncludingRegex = "includingRegex"
+	OperatorExcludingRegex = "excludingRegex"
+)
+
+// filterOperators maps the operator names accepted in filter expressions to API operators
+var filterOperators = map[string]string{
+	"equals":         OperatorEquals,
+	"notequals":      OperatorNotEquals,
+	"contains":       OperatorContains,
+	"notcontains":    OperatorNotContains,
+	"regex":          OperatorIncludingRegex,
+	"notregex":       OperatorExcludingRegex,
+	"includingregex": OperatorIncludingRegex,
+	"excludingregex": OperatorExcludingRegex,
+}
+
+// negatedOperators maps each operator to its logical complement
+var negatedOperators = map[string]string{
+	OperatorEquals:         OperatorNotEquals,
+	OperatorNotEquals:      OperatorEquals,
+	OperatorContains:       OperatorNotContains,
+	OperatorNotContains:    OperatorContains,
+	OperatorIncludingRegex: OperatorExcludingRegex,
+	OperatorExcludingRegex: OperatorIncludingRegex,
+}
+
+// filterDimensions lists the dimensions that can be filtered on
+var filterDimensions = map[string]bool{
+	"query": true, "page": true, "country": true, "device": true,
+}
+
+// FilterSyntaxError describes a problem in a filter expression
+type FilterSyntaxError struct {
+	Expr string
+	Pos  int
+	Msg  string
+}
+
+func (e *FilterSyntaxError) Error() string {
+	return fmt.Sprintf("invalid filter: %s\n  %s\n  %s^", e.Msg, e.Expr, strings.Repeat(" ", e.Pos))
+}
+
+// ParseFilter parses a filter expression into a list of filters that must all match.
+//
+// Terms take the form dimension:operator:value, where the operator is one of
+// equals, notEquals, contains, notContains, regex or notRegex. Without an
+// operator a value is matched exactly, or as a glob when it contains '*'.
+// Values containing spaces or parentheses must be quoted. Terms combine with
+// AND, OR, NOT and parentheses:
+//
+//	query:contains:shoes AND NOT query:regex:"^brand( name)?"
+//	page:*/blog/* OR page:*/news/*
+//
+// The API ANDs every filter together, so OR is only possible between
+// positive terms on the same dimension; those are folded into one regex.
+func ParseFilter(expr string) ([]Filter, error) {
+	p := &filterParser{expr: expr}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	if len(p.tokens) == 0 {
+		return nil, &FilterSyntaxError{Expr: expr, Pos: 0, Msg: "empty filter"}
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok != nil {
+		return nil, p.errorAt(tok.pos, fmt.Sprintf("unexpected %q", tok.text))
+	}
+
+	return p.compile(node, false)
+}
+
+type filterTokenKind int
+
+const (
+	tokenTerm filterTokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	pos  int
+}
+
+// filterNode is a node in the parsed expression tree
+type filterNode struct {
+	kind     filterTokenKind // tokenTerm, tokenAnd, tokenOr or tokenNot
+	filter   Filter
+	children []*filterNode
+	pos      int
+}
+
+type filterParser struct {
+	expr   string
+	tokens []filterToken
+	next   int
+}
+
+func (p *filterParser) errorAt(pos int, msg string) error {
+	return &FilterSyntaxError{Expr: p.expr, Pos: pos, Msg: msg}
+}
+
+// tokenize splits the expression into keywords, parentheses and terms
+func (p *filterParser) tokenize() error {
+	s := p.expr
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '(':
+			p.tokens = append(p.tokens, filterToken{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			p.tokens = append(p.tokens, filterToken{kind: tokenRParen, text: ")", pos: i})
+			i++
+		default:
+			start := i
+			for i < len(s) && !unicode.IsSpace(rune(s[i])) && s[i] != '(' && s[i] != ')' {
+				if s[i] == '"' || s[i] == '\'' {
+					end, err := p.skipQuoted(i)
+					if err != nil {
+						return err
+					}
+					i = end
+					continue
+				}
+				i++
+			}
+
+			text := s[start:i]
+			kind := tokenTerm
+			switch strings.ToUpper(text) {
+			case "AND":
+				kind = tokenAnd
+			case "OR":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+			p.tokens = append(p.tokens, filterToken{kind: kind, text: text, pos: start})
+		}
+	}
+	return nil
+}
+
+// skipQuoted returns the index just past the quoted string starting at i
+func (p *filterParser) skipQuoted(i int) (int, error) {
+	quote := p.expr[i]
+	for j := i + 1; j < len(p.expr); j++ {
+		switch p.expr[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1, nil
+		}
+	}
+	return 0, p.errorAt(i, "unterminated quoted string")
+}
+
+func (p *filterParser) peek() *filterToken {
+	if p.next >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.next]
+}
+
+// parseOr parses: and ( OR and )*
+func (p *filterParser) parseOr() (*filterNode, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &filterNode{kind: tokenOr, children: []*filterNode{first}, pos: first.pos}
+	for tok := p.peek(); tok != nil && tok.kind == tokenOr; tok = p.peek() {
+		p.next++
+		child, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+	}
+
+	if len(node.children) == 1 {
+		return first, nil
+	}
+	return node, nil
+}
+
+// parseAnd parses: unary ( [AND] unary )*
+func (p *filterParser) parseAnd() (*filterNode, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &filterNode{kind: tokenAnd, children: []*filterNode{first}, pos: first.pos}
+	for tok := p.peek(); tok != nil && tok.kind != tokenOr && tok.kind != tokenRParen; tok = p.peek() {
+		// Adjacent terms are implicitly ANDed
+		if tok.kind == tokenAnd {
+			p.next++
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+	}
+
+	if len(node.children) == 1 {
+		return first, nil
+	}
+	return node, nil
+}
+
+// parseUnary parses: NOT unary | ( or ) | term
+func (p *filterParser) parseUnary() (*filterNode, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, p.errorAt(len(p.expr), "unexpected end of filter, expected a term")
+	}
+
+	switch tok.kind {
+	case tokenNot:
+		p.next++
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &filterNode{kind: tokenNot, children: []*filterNode{child}, pos: tok.pos}, nil
+
+	case tokenLParen:
+		p.next++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing := p.peek()
+		if closing == nil || closing.kind != tokenRParen {
+			return nil, p.errorAt(tok.pos, "unbalanced parenthesis")
+		}
+		p.next++
+		return node, nil
+
+	case tokenTerm:
+		p.next++
+		f, err := p.parseTerm(tok)
+		if err != nil {
+			return nil, err
+		}
+		return &filterNode{kind: tokenTerm, filter: f, pos: tok.pos}, nil
+	}
+
+	return nil, p.errorAt(tok.pos, fmt.Sprintf("unexpected %q, expected a term", tok.text))
+}
+
+// parseTerm parses a dimension:operator:value or dimension:value term
+func (p *filterParser) parseTerm(tok *filterToken) (Filter, error) {
+	dimension, rest, ok := strings.Cut(tok.text, ":")
+	if !ok {
+		return Filter{}, p.errorAt(tok.pos, fmt.Sprintf("invalid term %q (expected dimension:operator:value)", tok.text))
+	}
+
+	dimension = strings.ToLower(dimension)
+	if !filterDimensions[dimension] {
+		return Filter{}, p.errorAt(tok.pos, fmt.Sprintf("invalid dimension %q (valid: query, page, country, device)", dimension))
+	}
+
+	valuePos := tok.pos + len(dimension) + 1
+	operator := ""
+	if name, value, ok := strings.Cut(rest, ":"); ok {
+		if op, known := filterOperators[strings.ToLower(name)]; known {
+			operator = op
+			rest = value
+			valuePos += len(name) + 1
+		}
+	}
+
+	value, quoted, err := unquoteFilterValue(rest)
+	if err != nil {
+		return Filter{}, p.errorAt(valuePos, err.Error())
+	}
+	if value == "" {
+		return Filter{}, p.errorAt(valuePos, "missing value")
+	}
+
+	if operator == "" {
+		operator = OperatorEquals
+		if !quoted && strings.Contains(value, "*") {
+			operator = OperatorIncludingRegex
+			value = globToRegex(value)
+		}
+	}
+
+	if operator == OperatorIncludingRegex || operator == OperatorExcludingRegex {
+		if _, err := regexp.Compile(value); err != nil {
+			return Filter{}, p.errorAt(valuePos, fmt.Sprintf("invalid regex: %v", err))
+		}
+	}
+
+	return Filter{Dimension: dimension, Operator: operator, Expression: value}, nil
+}
+
+// compile flattens the expression tree into ANDed filters, pushing NOT down
+// to the terms and folding same-dimension ORs into a single regex
+func (p *filterParser) compile(node *filterNode, negate bool) ([]Filter, error) {
+	switch node.kind {
+	case tokenTerm:
+		f := node.filter
+		if negate {
+			f.Operator = negatedOperators[f.Operator]
+		}
+		return []Filter{f}, nil
+
+	case tokenNot:
+		return p.compile(node.children[0], !negate)
+
+	case tokenAnd, tokenOr:
+		// NOT (a AND b) would be NOT a OR NOT b, an OR of negated terms the
+		// API cannot express; say so rather than blame an OR nobody wrote
+		if node.kind == tokenAnd && negate {
+			return nil, p.errorAt(node.pos, "NOT cannot be applied to an AND group; negate each term instead")
+		}
+
+		// NOT (a OR b) is NOT a AND NOT b
+		if node.kind == tokenAnd || negate {
+			var filters []Filter
+			for _, child := range node.children {
+				fs, err := p.compile(child, negate)
+				if err != nil {
+					return nil, err
+				}
+				filters = append(filters, fs...)
+			}
+			return filters, nil
+		}
+
+		var alternatives []Filter
+		for _, child := range node.children {
+			fs, err := p.compile(child, negate)
+			if err != nil {
+				return nil, err
+			}
+			if len(fs) != 1 {
+				return nil, p.errorAt(child.pos, "OR can only combine single terms, not AND groups")
+			}
+			alternatives = append(alternatives, fs[0])
+		}
+		return p.foldOr(node, alternatives)
+	}
+
+	return nil, p.errorAt(node.pos, "invalid expression")
+}
+
+// foldOr merges ORed positive terms on one dimension into an includingRegex filter
+func (p *filterParser) foldOr(node *filterNode, alternatives []Filter) ([]Filter, error) {
+	dimension := alternatives[0].Dimension
+	patterns := make([]string, 0, len(alternatives))
+	for _, f := range alternatives {
+		if f.Dimension != dimension {
+			return nil, p.errorAt(node.pos, fmt.Sprintf("OR across different dimensions (%s, %s) is not supported by the Search Console API", dimension, f.Dimension))
+		}
+
+		switch f.Operator {
+		case OperatorEquals:
+			patterns = append(patterns, "^"+regexp.QuoteMeta(f.Expression)+"$")
+		case OperatorContains:
+			patterns = append(patterns, "(?i:"+regexp.QuoteMeta(f.Expression)+")")
+		case OperatorIncludingRegex:
+			patterns = append(patterns, "(?:"+f.Expression+")")
+		default:
+			return nil, p.errorAt(node.pos, fmt.Sprintf("OR cannot combine negated terms (%s)", f.Operator))
+		}
+	}
+
+	return []Filter{{
+		Dimension:  dimension,
+		Operator:   OperatorIncludingRegex,
+		Expression: strings.Join(patterns, "|"),
+	}}, nil
+}
+
+// unquoteFilterValue strips surrounding quotes and resolves backslash escapes
+func unquoteFilterValue(s string) (value string, quoted bool, err error) {
+	if s == "" || (s[0] != '"' && s[0] != '\'') {
+		return s, false, nil
+	}
+
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case quote:
+			if i != len(s)-1 {
+				return "", false, fmt.Errorf("unexpected text after closing quote")
+			}
+			return b.String(), true, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", false, fmt.Errorf("unterminated quoted string")
+}
+
+// globToRegex converts a '*' glob into a regex, escaping everything else.
+// Like the API's regex filters, the result is unanchored, so query:shoe*
+// also matches "red shoes".
+func globToRegex(glob string) string {
+	parts := strings.Split(glob, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return strings.Join(parts, ".*")
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want []Filter
+	}{
+		{"exact", "query:shoes", []Filter{{"query", OperatorEquals, "shoes"}}},
+		{"dimension case", "QUERY:shoes", []Filter{{"query", OperatorEquals, "shoes"}}},
+		{"operator", "query:contains:shoes", []Filter{{"query", OperatorContains, "shoes"}}},
+		{"unknown operator is part of the value", "page:https://example.com/", []Filter{{"page", OperatorEquals, "https://example.com/"}}},
+
+		// Globs are unanchored and escape everything but '*'
+		{"glob", "query:shoe*", []Filter{{"query", OperatorIncludingRegex, "shoe.*"}}},
+		{"glob path", "page:*/blog/*", []Filter{{"page", OperatorIncludingRegex, ".*/blog/.*"}}},
+		{"glob escapes", "page:*.html", []Filter{{"page", OperatorIncludingRegex, `.*\.html`}}},
+		{"quoted glob is literal", `query:"shoe*"`, []Filter{{"query", OperatorEquals, "shoe*"}}},
+
+		// Quoting and escapes
+		{"double quotes", `query:contains:"running shoes"`, []Filter{{"query", OperatorContains, "running shoes"}}},
+		{"single quotes", `query:'a (b)'`, []Filter{{"query", OperatorEquals, "a (b)"}}},
+		{"escaped quote", `query:"say \"hi\""`, []Filter{{"query", OperatorEquals, `say "hi"`}}},
+		{"escaped backslash", `query:'a\\b'`, []Filter{{"query", OperatorEquals, `a\b`}}},
+
+		// AND, implicit AND and NOT
+		{"and", "query:a AND device:MOBILE", []Filter{{"query", OperatorEquals, "a"}, {"device", OperatorEquals, "MOBILE"}}},
+		{"implicit and", "query:a device:MOBILE", []Filter{{"query", OperatorEquals, "a"}, {"device", OperatorEquals, "MOBILE"}}},
+		{"lowercase keywords", "query:a and not device:MOBILE", []Filter{{"query", OperatorEquals, "a"}, {"device", OperatorNotEquals, "MOBILE"}}},
+		{"not", `query:contains:shoes AND NOT query:regex:"^brand( name)?"`, []Filter{
+			{"query", OperatorContains, "shoes"},
+			{"query", OperatorExcludingRegex, "^brand( name)?"},
+		}},
+		{"not contains", "NOT query:contains:x", []Filter{{"query", OperatorNotContains, "x"}}},
+		{"double not", "NOT NOT query:a", []Filter{{"query", OperatorEquals, "a"}}},
+		{"not pushed into or", "NOT (query:a OR query:contains:b)", []Filter{
+			{"query", OperatorNotEquals, "a"},
+			{"query", OperatorNotContains, "b"},
+		}},
+		{"not not and", "NOT NOT (query:a device:MOBILE)", []Filter{{"query", OperatorEquals, "a"}, {"device", OperatorEquals, "MOBILE"}}},
+
+		// OR folds into one regex and binds looser than AND
+		{"or", "query:a OR query:b", []Filter{{"query", OperatorIncludingRegex, "^a$|^b$"}}},
+		{"or kinds", "query:equals:a.b OR query:contains:c OR query:regex:x+ OR query:d*", []Filter{
+			{"query", OperatorIncludingRegex, `^a\.b$|(?i:c)|(?:x+)|(?:d.*)`},
+		}},
+		{"or then and", "(query:a OR query:b) device:MOBILE", []Filter{
+			{"query", OperatorIncludingRegex, "^a$|^b$"},
+			{"device", OperatorEquals, "MOBILE"},
+		}},
+		{"nested or", "page:a OR (page:b OR page:c)", []Filter{{"page", OperatorIncludingRegex, "^a$|(?:^b$|^c$)"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) error: %v", tt.expr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFilter(%q) = %+v, want %+v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		pos  int
+		msg  string
+	}{
+		{"empty", "  ", 0, "empty filter"},
+		{"unterminated quote", `query:"abc`, 6, "unterminated quoted string"},
+		{"text after quote", `query:"a"b`, 6, "unexpected text after closing quote"},
+		{"missing value", "query:contains:", 15, "missing value"},
+		{"no dimension", "shoes", 0, "invalid term"},
+		{"bad dimension", "date:2025-01-01", 0, "invalid dimension"},
+		{"bad regex", `query:regex:"a("`, 12, "invalid regex"},
+		{"unclosed paren", "(query:a", 0, "unbalanced parenthesis"},
+		{"stray paren", "query:a)", 7, `unexpected ")"`},
+		{"dangling and", "query:a AND", 11, "unexpected end of filter"},
+		{"leading and", "AND query:a", 0, `unexpected "AND"`},
+		{"or across dimensions", "page:a OR query:b", 0, "OR across different dimensions (page, query)"},
+		{"or of negated term", "query:a OR NOT query:b", 0, "OR cannot combine negated terms"},
+		{"or binds looser than and", "device:MOBILE query:a OR query:b", 0, "OR can only combine single terms"},
+		{"or of and group", "query:a OR (query:b device:MOBILE)", 12, "OR can only combine single terms"},
+		{"not over and", "NOT (query:a AND query:b)", 5, "NOT cannot be applied to an AND group"},
+		{"not over implicit and", "query:x NOT (query:a device:MOBILE)", 13, "NOT cannot be applied to an AND group"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFilter(tt.expr)
+			var syntaxErr *FilterSyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("ParseFilter(%q) error = %v, want a FilterSyntaxError", tt.expr, err)
+			}
+			if !strings.Contains(syntaxErr.Msg, tt.msg) {
+				t.Errorf("message = %q, want it to contain %q", syntaxErr.Msg, tt.msg)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("position = %d, want %d", syntaxErr.Pos, tt.pos)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/searchconsole/v1"
+)
+
+// Data states for Search Analytics queries
+const (
+	DataStateFinal = "final" // complete data only
+	DataStateAll   = "all"   // include fresh, partial data
+)
+
+// freshnessWindow is how many days back the freshness probe looks
+const freshnessWindow = 10
+
+// Freshness describes the most recent data available for a site
+type Freshness struct {
+	LatestDate          string // last day with data
+	FirstIncompleteDate string // first day still being processed (fresh data only)
+	Partial             bool   // whether LatestDate is incomplete
+}
+
+// IncompleteFrom returns the first day whose data may still change: the
+// reported first incomplete date, or else the day after the latest date
+func (f *Freshness) IncompleteFrom() string {
+	if f.FirstIncompleteDate != "" {
+		return f.FirstIncompleteDate
+	}
+	d, err := time.Parse(dateLayout, f.LatestDate)
+	if err != nil {
+		return ""
+	}
+	return d.AddDate(0, 0, 1).Format(dateLayout)
+}
+
+// ProbeFreshness finds the last day with data by grouping recent days by date.
+// With includeFresh, partial data counts and the first incomplete day is reported.
+func (c *Client) ProbeFreshness(ctx context.Context, searchType string, includeFresh bool) (*Freshness, error) {
+	now := time.Now()
+	dataState := DataStateFinal
+	if includeFresh {
+		dataState = DataStateAll
+	}
+
+	searchType, err := NormalizeSearchType(searchType)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.service.Searchanalytics.Query(c.siteURL, &searchconsole.SearchAnalyticsQueryRequest{
+		StartDate:  now.AddDate(0, 0, -freshnessWindow).Format(dateLayout),
+		EndDate:    now.Format(dateLayout),
+		Dimensions: []string{"date"},
+		RowLimit:   freshnessWindow + 1,
+		Type:       searchType,
+		DataState:  dataState,
+	}).Context(ctx).Do()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("could not check data freshness: %w", c.classifySiteError(ctx, err))
+	}
+
+	var latest string
+	for _, row := range resp.Rows {
+		if len(row.Keys) > 0 && row.Keys[0] > latest {
+			latest = row.Keys[0]
+		}
+	}
+
+	// Fall back to the usual delay when the window has no data at all
+	if latest == "" {
+		return &Freshness{LatestDate: now.AddDate(0, 0, -dataDelay).Format(dateLayout)}, nil
+	}
+
+	f := &Freshness{LatestDate: latest}
+	if resp.Metadata != nil && resp.Metadata.FirstIncompleteDate != "" {
+		f.FirstIncompleteDate = resp.Metadata.FirstIncompleteDate
+		f.Partial = latest >= f.FirstIncompleteDate
+	}
+
+	return f, nil
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// maxRetries is how many times a failed request is retried
+	maxRetries = 5
+	// baseBackoff and maxBackoff bound the exponential backoff between retries
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 30 * time.Second
+	// maxRetryAfter is the longest Retry-After we wait out; longer means
+	// the quota will not recover soon, so the error is returned instead
+	maxRetryAfter = 2 * time.Minute
+)
+
+// Search Console allows 1,200 queries per minute per site and per user.
+// Stay a little under both so bursts of pagination do not trip them.
+const (
+	siteQPS      = 18
+	userQPS      = 18
+	limiterBurst = 5
+)
+
+var (
+	userLimiter = rate.NewLimiter(userQPS, limiterBurst)
+
+	siteLimitersMu sync.Mutex
+	siteLimiters   = make(map[string]*rate.Limiter)
+)
+
+// siteLimiter returns the rate limiter shared by all requests for a site
+func siteLimiter(site string) *rate.Limiter {
+	siteLimitersMu.Lock()
+	defer siteLimitersMu.Unlock()
+	l, ok := siteLimiters[site]
+	if !ok {
+		l = rate.NewLimiter(siteQPS, limiterBurst)
+		siteLimiters[site] = l
+	}
+	return l
+}
+
+// retryTransport rate-limits requests and retries transient failures
+// (429, 403 rate limits, 5xx and network errors) with jittered exponential
+// backoff
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := userLimiter.Wait(ctx); err != nil {
+			return nil, limiterError(ctx)
+		}
+		if site := requestSite(req); site != "" {
+			if err := siteLimiter(site).Wait(ctx); err != nil {
+				return nil, limiterError(ctx)
+			}
+		}
+
+		r := req
+		if attempt > 0 {
+			r = req.Clone(ctx)
+			if req.Body != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if !shouldRetry(resp, err) || attempt >= maxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		delay := backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				if after > maxRetryAfter {
+					return resp, nil
+				}
+				delay = after
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// limiterError returns why waiting for the rate limiter failed. The limiter
+// gives up early when the context deadline would pass before a slot frees up.
+func limiterError(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return context.DeadlineExceeded
+}
+
+// shouldRetry reports whether a request failed in a way worth retrying
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	case http.StatusForbidden:
+		return rateLimited(resp)
+	}
+	return false
+}
+
+// rateLimitReasons are the 403 error reasons for short-term rate limits,
+// which pass like a 429. Other quota reasons last until the quota resets.
+var rateLimitReasons = map[string]bool{
+	"rateLimitExceeded":     true,
+	"userRateLimitExceeded": true,
+}
+
+// maxErrorBody caps how much of an error response is read to find its reason
+const maxErrorBody = 64 << 10
+
+// rateLimited reports whether an error response is a short-term rate limit.
+// The body is read and replaced so the caller can still decode it.
+func rateLimited(resp *http.Response) bool {
+	orig := resp.Body
+	body, err := io.ReadAll(io.LimitReader(orig, maxErrorBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), orig), orig}
+	if err != nil {
+		return false
+	}
+
+	var apiErr struct {
+		Error struct {
+			Errors []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) != nil {
+		return false
+	}
+	for _, item := range apiErr.Error.Errors {
+		if rateLimitReasons[item.Reason] {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns a random delay up to baseBackoff*2^attempt ("full jitter")
+func backoff(attempt int) time.Duration {
+	d := baseBackoff << attempt
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d))) + time.Millisecond
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// requestSite extracts the site from a .../sites/{siteUrl}/... request path
+func requestSite(req *http.Request) string {
+	_, rest, ok := strings.Cut(req.URL.EscapedPath(), "/sites/")
+	if !ok {
+		return ""
+	}
+	site, _, _ := strings.Cut(rest, "/")
+	return site
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRetryTransportForbidden(t *testing.T) {
+	tests := []struct {
+		name      string
+		reason    string
+		wantCalls int32
+		wantCode  int
+	}{
+		{"rate limit", "rateLimitExceeded", 2, http.StatusOK},
+		{"user rate limit", "userRateLimitExceeded", 2, http.StatusOK},
+		{"daily quota", "dailyLimitExceeded", 1, http.StatusForbidden},
+		{"permission", "forbidden", 1, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusForbidden)
+					io.WriteString(w, `{"error":{"code":403,"message":"denied","errors":[{"reason":"`+tt.reason+`"}]}}`)
+					return
+				}
+				io.WriteString(w, "ok")
+			}))
+			defer srv.Close()
+
+			client := &http.Client{Transport: &retryTransport{base: http.DefaultTransport}}
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+			// The error body must still be readable after its reason was checked
+			if resp.StatusCode == http.StatusForbidden && !strings.Contains(string(body), tt.reason) {
+				t.Errorf("body = %q, want the original error", body)
+			}
+		})
+	}
+}
@@ -1,12 +1,35 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"time"
+	"iter"
+	"strings"
 
 	"google.golang.org/api/searchconsole/v1"
 )
 
+// Search types supported by the Search Analytics API
+const (
+	SearchTypeWeb        = "web"
+	SearchTypeImage      = "image"
+	SearchTypeVideo      = "video"
+	SearchTypeNews       = "news"
+	SearchTypeDiscover   = "discover"
+	SearchTypeGoogleNews = "googleNews"
+)
+
+// SearchTypes lists every valid search type in display order
+var SearchTypes = []string{
+	SearchTypeWeb,
+	SearchTypeImage,
+	SearchTypeVideo,
+	SearchTypeNews,
+	SearchTypeDiscover,
+	SearchTypeGoogleNews,
+}
+
 // QueryRequest represents parameters for a Search Analytics query
 type QueryRequest struct {
 	StartDate  string
@@ -15,6 +38,8 @@ type QueryRequest struct {
 	RowLimit   int64
 	StartRow   int64
 	Filters    []Filter
+	SearchType string // web, image, video, news, discover, googleNews
+	DataState  string // final (default) or all to include fresh data
 }
 
 // Filter represents a dimension filter
@@ -37,16 +62,100 @@ type QueryRow struct {
 	Position    float64
 }
 
+// Dimension returns the row's value for the named dimension
+func (r QueryRow) Dimension(dim string) string {
+	switch dim {
+	case "query":
+		return r.Query
+	case "page":
+		return r.Page
+	case "country":
+		return r.Country
+	case "device":
+		return r.Device
+	case "date":
+		return r.Date
+	}
+	return ""
+}
+
 // QueryResult represents the result of a Search Analytics query
 type QueryResult struct {
-	Rows      []QueryRow
-	TotalRows int
-	StartDate string
-	EndDate   string
+	Rows                []QueryRow
+	TotalRows           int
+	StartDate           string
+	EndDate             string
+	SearchType          string
+	FirstIncompleteDate string // set for fresh data grouped by date
+}
+
+// NormalizeSearchType returns the canonical spelling of a search type,
+// defaulting to web when empty
+func NormalizeSearchType(searchType string) (string, error) {
+	if searchType == "" {
+		return SearchTypeWeb, nil
+	}
+	for _, t := range SearchTypes {
+		if strings.EqualFold(searchType, t) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("invalid search type: %s (valid: %s)", searchType, strings.Join(SearchTypes, ", "))
+}
+
+// HasQueryData reports whether the search type exposes the query dimension.
+// Discover and Google News do not report queries.
+func HasQueryData(searchType string) bool {
+	return searchType != SearchTypeDiscover && searchType != SearchTypeGoogleNews
+}
+
+// HasPosition reports whether the search type reports average position.
+// Discover and Google News do not rank results by position.
+func HasPosition(searchType string) bool {
+	return searchType != SearchTypeDiscover && searchType != SearchTypeGoogleNews
+}
+
+// ValidateSearchType checks that the dimensions used for grouping or
+// filtering are supported by the search type
+func ValidateSearchType(searchType string, dimensions []string) error {
+	searchType, err := NormalizeSearchType(searchType)
+	if err != nil {
+		return err
+	}
+	if HasQueryData(searchType) {
+		return nil
+	}
+	for _, dim := range dimensions {
+		if dim == "query" {
+			return fmt.Errorf("dimension %q is not supported for search type %s", dim, searchType)
+		}
+	}
+	return nil
+}
+
+// Validate normalizes the search type and rejects dimensions or filters
+// the search type cannot report on
+func (r *QueryRequest) Validate() error {
+	searchType, err := NormalizeSearchType(r.SearchType)
+	if err != nil {
+		return err
+	}
+	r.SearchType = searchType
+
+	dims := append([]string{}, r.Dimensions...)
+	for _, f := range r.Filters {
+		dims = append(dims, f.Dimension)
+	}
+	return ValidateSearchType(searchType, dims)
 }
 
 // Query executes a Search Analytics query
-func (c *Client) Query(req QueryRequest) (*QueryResult, error) {
+func (c *Client) Query(ctx context.Context, req QueryRequest) (*QueryResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	searchType := req.SearchType
+
 	// Build the API request
 	apiReq := &searchconsole.SearchAnalyticsQueryRequest{
 		StartDate:  req.StartDate,
@@ -54,6 +163,8 @@ func (c *Client) Query(req QueryRequest) (*QueryResult, error) {
 		Dimensions: req.Dimensions,
 		RowLimit:   req.RowLimit,
 		StartRow:   req.StartRow,
+		Type:       searchType,
+		DataState:  req.DataState,
 	}
 
 	// Set defaults
@@ -64,7 +175,8 @@ func (c *Client) Query(req QueryRequest) (*QueryResult, error) {
 		apiReq.Dimensions = []string{"query"}
 	}
 
-	// Add filters
+	// Add filters. The API only supports AND groups, so ParseFilter has
+	// already folded any OR expressions into single regex filters.
 	if len(req.Filters) > 0 {
 		var dimensionFilters []*searchconsole.ApiDimensionFilter
 		for _, f := range req.Filters {
@@ -83,16 +195,23 @@ func (c *Client) Query(req QueryRequest) (*QueryResult, error) {
 	}
 
 	// Execute query
-	resp, err := c.service.Searchanalytics.Query(c.siteURL, apiReq).Do()
+	resp, err := c.service.Searchanalytics.Query(c.siteURL, apiReq).Context(ctx).Do()
 	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("query failed: %w", c.classifySiteError(ctx, err))
 	}
 
 	// Parse results
 	result := &QueryResult{
-		TotalRows: len(resp.Rows),
-		StartDate: req.StartDate,
-		EndDate:   req.EndDate,
+		TotalRows:  len(resp.Rows),
+		StartDate:  req.StartDate,
+		EndDate:    req.EndDate,
+		SearchType: searchType,
+	}
+	if resp.Metadata != nil {
+		result.FirstIncompleteDate = resp.Metadata.FirstIncompleteDate
 	}
 
 	for _, row := range resp.Rows {
@@ -127,96 +246,114 @@ func (c *Client) Query(req QueryRequest) (*QueryResult, error) {
 	return result, nil
 }
 
-// QueryAll fetches all results with pagination
-func (c *Client) QueryAll(req QueryRequest) (*QueryResult, error) {
-	var allRows []QueryRow
-	startRow := int64(0)
-	batchSize := int64(25000) // Max allowed by API
+// All iterates over the rows of a result, like Client.Rows does for a query
+func (r *QueryResult) All() iter.Seq2[QueryRow, error] {
+	return func(yield func(QueryRow, error) bool) {
+		for _, row := range r.Rows {
+			if !yield(row, nil) {
+				return
+			}
+		}
+	}
+}
 
-	for {
-		req.StartRow = startRow
-		req.RowLimit = batchSize
+// ProgressFunc is called after each page of results with the running row count
+type ProgressFunc func(fetched int)
 
-		result, err := c.Query(req)
-		if err != nil {
-			return nil, err
-		}
+// QueryAll fetches all results with pagination
+func (c *Client) QueryAll(ctx context.Context, req QueryRequest) (*QueryResult, error) {
+	return c.QueryAllWithProgress(ctx, req, nil)
+}
 
-		allRows = append(allRows, result.Rows...)
+// QueryAllWithProgress fetches all results with pagination, reporting progress after each page.
+// If fetching stops early, the rows fetched so far are returned along with the error.
+func (c *Client) QueryAllWithProgress(ctx context.Context, req QueryRequest, progress ProgressFunc) (*QueryResult, error) {
+	all := &QueryResult{
+		StartDate:  req.StartDate,
+		EndDate:    req.EndDate,
+		SearchType: req.SearchType,
+	}
 
-		if len(result.Rows) < int(batchSize) {
-			break
+	err := c.eachPage(ctx, req, func(page *QueryResult) error {
+		all.Rows = append(all.Rows, page.Rows...)
+		all.TotalRows = len(all.Rows)
+		all.SearchType = page.SearchType
+		if page.FirstIncompleteDate != "" {
+			all.FirstIncompleteDate = page.FirstIncompleteDate
 		}
-
-		startRow += batchSize
+		if progress != nil {
+			progress(len(all.Rows))
+		}
+		return nil
+	})
+	if err != nil {
+		if len(all.Rows) == 0 {
+			return nil, err
+		}
+		return all, err
 	}
 
-	return &QueryResult{
-		Rows:      allRows,
-		TotalRows: len(allRows),
-		StartDate: req.StartDate,
-		EndDate:   req.EndDate,
-	}, nil
+	return all, nil
 }
 
-// DefaultDateRange returns the default date range (last 28 days)
-func DefaultDateRange() (start, end string) {
-	now := time.Now()
-	end = now.AddDate(0, 0, -3).Format("2006-01-02")    // 3 days ago (data delay)
-	start = now.AddDate(0, 0, -30).Format("2006-01-02") // 30 days ago
-	return
-}
+// Rows streams every row of a query, fetching one page at a time so memory
+// stays constant however many rows there are. A positive RowLimit caps the
+// total number of rows. Iteration stops at the first error, which is
+// yielded with a zero row.
+func (c *Client) Rows(ctx context.Context, req QueryRequest, progress ProgressFunc) iter.Seq2[QueryRow, error] {
+	return func(yield func(QueryRow, error) bool) {
+		limit := req.RowLimit
+		fetched := 0
 
-// DateRangeForDays returns a date range for the last N days
-func DateRangeForDays(days int) (start, end string) {
-	now := time.Now()
-	end = now.AddDate(0, 0, -3).Format("2006-01-02") // 3 days ago (data delay)
-	start = now.AddDate(0, 0, -3-days).Format("2006-01-02")
-	return
+		err := c.eachPage(ctx, req, func(page *QueryResult) error {
+			for _, row := range page.Rows {
+				if limit > 0 && int64(fetched) >= limit {
+					return errStopPaging
+				}
+				fetched++
+				if !yield(row, nil) {
+					return errStopPaging
+				}
+			}
+			if progress != nil {
+				progress(fetched)
+			}
+			if limit > 0 && int64(fetched) >= limit {
+				return errStopPaging
+			}
+			return nil
+		})
+		if err != nil && err != errStopPaging {
+			yield(QueryRow{}, err)
+		}
+	}
 }
 
-// ComparisonPeriod represents two date ranges for comparison
-type ComparisonPeriod struct {
-	CurrentStart  string
-	CurrentEnd    string
-	PreviousStart string
-	PreviousEnd   string
-}
+// errStopPaging ends eachPage early without an error
+var errStopPaging = errors.New("stop paging")
 
-// GetComparisonPeriod returns date ranges for week-over-week or month-over-month comparison
-func GetComparisonPeriod(period string) ComparisonPeriod {
-	now := time.Now()
-	dataDelay := 3 // GSC data is typically 3 days behind
+// eachPage fetches every page of a query in turn, calling fn with each one
+func (c *Client) eachPage(ctx context.Context, req QueryRequest, fn func(*QueryResult) error) error {
+	startRow := int64(0)
+	batchSize := int64(25000) // Max allowed by API
 
-	switch period {
-	case "week":
-		currentEnd := now.AddDate(0, 0, -dataDelay)
-		currentStart := currentEnd.AddDate(0, 0, -6)
-		previousEnd := currentStart.AddDate(0, 0, -1)
-		previousStart := previousEnd.AddDate(0, 0, -6)
+	for {
+		req.StartRow = startRow
+		req.RowLimit = batchSize
 
-		return ComparisonPeriod{
-			CurrentStart:  currentStart.Format("2006-01-02"),
-			CurrentEnd:    currentEnd.Format("2006-01-02"),
-			PreviousStart: previousStart.Format("2006-01-02"),
-			PreviousEnd:   previousEnd.Format("2006-01-02"),
+		page, err := c.Query(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(page); err != nil {
+			return err
 		}
 
-	case "month":
-		currentEnd := now.AddDate(0, 0, -dataDelay)
-		currentStart := currentEnd.AddDate(0, 0, -29)
-		previousEnd := currentStart.AddDate(0, 0, -1)
-		previousStart := previousEnd.AddDate(0, 0, -29)
-
-		return ComparisonPeriod{
-			CurrentStart:  currentStart.Format("2006-01-02"),
-			CurrentEnd:    currentEnd.Format("2006-01-02"),
-			PreviousStart: previousStart.Format("2006-01-02"),
-			PreviousEnd:   previousEnd.Format("2006-01-02"),
+		if len(page.Rows) < int(batchSize) {
+			return nil
 		}
 
-	default:
-		// Default to week
-		return GetComparisonPeriod("week")
+		startRow += batchSize
 	}
 }
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Shard modes for splitting a query into smaller requests
+const (
+	ShardDay     = "day"
+	ShardDevice  = "device"
+	ShardCountry = "country"
+)
+
+// ShardModes lists the supported shard modes
+var ShardModes = []string{ShardDay, ShardDevice, ShardCountry}
+
+// DefaultConcurrency is how many shards are fetched at once by default
+const DefaultConcurrency = 4
+
+// devices are the values of the device dimension
+var devices = []string{"DESKTOP", "MOBILE", "TABLET"}
+
+// Shard is one slice of a sharded query
+type Shard struct {
+	Label   string // day, device or country the shard covers
+	Request QueryRequest
+}
+
+// ShardRequest splits req into one request per day, device or country.
+// Country shards need the list of countries, so q is asked for it first.
+func ShardRequest(ctx context.Context, q Querier, req QueryRequest, mode string) ([]Shard, error) {
+	var shards []Shard
+
+	switch mode {
+	case ShardDay:
+		days, err := DaysBetween(req.StartDate, req.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		for _, day := range days {
+			r := req
+			r.StartDate, r.EndDate = day, day
+			shards = append(shards, Shard{Label: day, Request: r})
+		}
+
+	case ShardDevice, ShardCountry:
+		for _, f := range req.Filters {
+			if f.Dimension == mode {
+				return nil, fmt.Errorf("cannot shard by %s when filtering on %s", mode, mode)
+			}
+		}
+
+		values := devices
+		if mode == ShardCountry {
+			countries, err := q.QueryAll(ctx, QueryRequest{
+				StartDate:  req.StartDate,
+				EndDate:    req.EndDate,
+				Dimensions: []string{"country"},
+				Filters:    req.Filters,
+				SearchType: req.SearchType,
+				DataState:  req.DataState,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("could not list countries: %w", err)
+			}
+			values = nil
+			for _, row := range countries.Rows {
+				values = append(values, row.Country)
+			}
+		}
+
+		for _, value := range values {
+			r := req
+			r.Filters = append(append([]Filter(nil), req.Filters...), Filter{
+				Dimension:  mode,
+				Operator:   OperatorEquals,
+				Expression: value,
+			})
+			shards = append(shards, Shard{Label: value, Request: r})
+		}
+
+	default:
+		return nil, fmt.Errorf("invalid shard mode: %s (valid: %s)", mode, strings.Join(ShardModes, ", "))
+	}
+
+	return shards, nil
+}
+
+// FetchShards fetches every row of each shard, running up to concurrency
+// shards at once. handle is called for each finished shard, one at a time.
+// The first error cancels the remaining shards.
+func FetchShards(ctx context.Context, q Querier, shards []Shard, concurrency int, progress ProgressFunc, handle func(Shard, *QueryResult) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		fetched  int
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	sem := make(chan struct{}, concurrency)
+	for _, shard := range shards {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(shard Shard) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var done int
+			result, err := q.QueryAllWithProgress(ctx, shard.Request, func(n int) {
+				if progress == nil {
+					return
+				}
+				mu.Lock()
+				fetched += n - done
+				done = n
+				progress(fetched)
+				mu.Unlock()
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if firstErr != nil {
+				return
+			}
+			if err != nil {
+				fail(fmt.Errorf("%s: %w", shard.Label, err))
+				return
+			}
+			if err := handle(shard, result); err != nil {
+				fail(err)
+			}
+		}(shard)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// QuerySharded runs req as shards and merges their rows. Rows that share
+// the same dimension values are combined: clicks and impressions are summed,
+// CTR is recomputed and position is weighted by impressions. RowLimit is
+// applied after merging. If a shard fails, the rows merged from the shards
+// that finished are returned along with the error.
+func QuerySharded(ctx context.Context, q Querier, req QueryRequest, mode string, concurrency int, progress ProgressFunc) (*QueryResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	shards, err := ShardRequest(ctx, q, req, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	dimensions := req.Dimensions
+	if len(dimensions) == 0 {
+		dimensions = []string{"query"}
+	}
+
+	merged := &QueryResult{
+		StartDate:  req.StartDate,
+		EndDate:    req.EndDate,
+		SearchType: req.SearchType,
+	}
+	index := make(map[string]int)
+	weighted := []float64{}
+
+	err = FetchShards(ctx, q, shards, concurrency, progress, func(shard Shard, result *QueryResult) error {
+		if result.FirstIncompleteDate != "" &&
+			(merged.FirstIncompleteDate == "" || result.FirstIncompleteDate < merged.FirstIncompleteDate) {
+			merged.FirstIncompleteDate = result.FirstIncompleteDate
+		}
+
+		for _, row := range result.Rows {
+			key := rowKey(row, dimensions)
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(merged.Rows)
+				merged.Rows = append(merged.Rows, row)
+				weighted = append(weighted, row.Position*row.Impressions)
+				continue
+			}
+			m := &merged.Rows[i]
+			m.Clicks += row.Clicks
+			m.Impressions += row.Impressions
+			weighted[i] += row.Position * row.Impressions
+		}
+		return nil
+	})
+
+	for i := range merged.Rows {
+		m := &merged.Rows[i]
+		if m.Impressions > 0 {
+			m.CTR = m.Clicks / m.Impressions
+			m.Position = weighted[i] / m.Impressions
+		}
+	}
+
+	sort.SliceStable(merged.Rows, func(i, j int) bool {
+		a, b := merged.Rows[i], merged.Rows[j]
+		if a.Clicks != b.Clicks {
+			return a.Clicks > b.Clicks
+		}
+		return a.Impressions > b.Impressions
+	})
+
+	merged.TotalRows = len(merged.Rows)
+	if req.RowLimit > 0 && int64(len(merged.Rows)) > req.RowLimit {
+		merged.Rows = merged.Rows[:req.RowLimit]
+	}
+
+	return merged, err
+}
+
+// rowKey joins a row's dimension values into a map key
+func rowKey(row QueryRow, dimensions []string) string {
+	keys := make([]string, len(dimensions))
+	for i, dim := range dimensions {
+		keys[i] = row.Dimension(dim)
+	}
+	return strings.Join(keys, "\x00")
+}
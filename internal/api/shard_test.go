@@ -0,0 +1,268 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// shardQuerier answers shard requests with canned rows, keyed by the day,
+// device or country the request covers
+type shardQuerier struct {
+	rows       map[string][]QueryRow
+	incomplete map[string]string // first incomplete date by shard
+	fail       string            // shard that fails
+	delay      time.Duration
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	requests    []string
+}
+
+// label returns the shard a request covers: its last device or country
+// filter, or its day
+func (q *shardQuerier) label(req QueryRequest) string {
+	if n := len(req.Filters); n > 0 {
+		if f := req.Filters[n-1]; f.Dimension == ShardDevice || f.Dimension == ShardCountry {
+			return f.Expression
+		}
+	}
+	return req.StartDate
+}
+
+func (q *shardQuerier) Query(ctx context.Context, req QueryRequest) (*QueryResult, error) {
+	return q.QueryAllWithProgress(ctx, req, nil)
+}
+
+func (q *shardQuerier) QueryAll(ctx context.Context, req QueryRequest) (*QueryResult, error) {
+	// Listing countries for country shards
+	if reflect.DeepEqual(req.Dimensions, []string{"country"}) {
+		var result QueryResult
+		for country := range q.rows {
+			result.Rows = append(result.Rows, QueryRow{Country: country})
+		}
+		return &result, nil
+	}
+	return q.QueryAllWithProgress(ctx, req, nil)
+}
+
+func (q *shardQuerier) QueryAllWithProgress(ctx context.Context, req QueryRequest, progress ProgressFunc) (*QueryResult, error) {
+	label := q.label(req)
+
+	q.mu.Lock()
+	q.inFlight++
+	q.maxInFlight = max(q.maxInFlight, q.inFlight)
+	q.requests = append(q.requests, label)
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		q.inFlight--
+		q.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(q.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if label == q.fail {
+		return nil, errors.New("backend error")
+	}
+	rows := q.rows[label]
+	if progress != nil {
+		progress(len(rows))
+	}
+	return &QueryResult{Rows: rows, FirstIncompleteDate: q.incomplete[label]}, nil
+}
+
+func (q *shardQuerier) Rows(ctx context.Context, req QueryRequest, progress ProgressFunc) iter.Seq2[QueryRow, error] {
+	return func(yield func(QueryRow, error) bool) {}
+}
+
+func (q *shardQuerier) ProbeFreshness(ctx context.Context, searchType string, includeFresh bool) (*Freshness, error) {
+	return &Freshness{}, nil
+}
+
+func TestShardRequest(t *testing.T) {
+	ctx := context.Background()
+	filter := Filter{Dimension: "query", Operator: OperatorContains, Expression: "shoes"}
+	req := QueryRequest{StartDate: "2026-02-27", EndDate: "2026-03-01", Filters: []Filter{filter}, SearchType: "web"}
+	q := &shardQuerier{rows: map[string][]QueryRow{"usa": nil, "deu": nil}}
+
+	labels := func(shards []Shard) []string {
+		var out []string
+		for _, s := range shards {
+			out = append(out, s.Label)
+		}
+		return out
+	}
+
+	days, err := ShardRequest(ctx, q, req, ShardDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := labels(days), []string{"2026-02-27", "2026-02-28", "2026-03-01"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("day shards = %v, want %v", got, want)
+	}
+	for _, s := range days {
+		if s.Request.StartDate != s.Label || s.Request.EndDate != s.Label {
+			t.Errorf("shard %s covers %s to %s", s.Label, s.Request.StartDate, s.Request.EndDate)
+		}
+	}
+
+	byDevice, err := ShardRequest(ctx, q, req, ShardDevice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := labels(byDevice), devices; !reflect.DeepEqual(got, want) {
+		t.Errorf("device shards = %v, want %v", got, want)
+	}
+	for _, s := range byDevice {
+		want := []Filter{filter, {Dimension: "device", Operator: OperatorEquals, Expression: s.Label}}
+		if !reflect.DeepEqual(s.Request.Filters, want) {
+			t.Errorf("shard %s filters = %v, want %v", s.Label, s.Request.Filters, want)
+		}
+	}
+	if len(req.Filters) != 1 {
+		t.Errorf("sharding changed the request's filters: %v", req.Filters)
+	}
+
+	byCountry, err := ShardRequest(ctx, q, req, ShardCountry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := labels(byCountry)
+	if len(got) != 2 || !strings.Contains(strings.Join(got, ","), "usa") || !strings.Contains(strings.Join(got, ","), "deu") {
+		t.Errorf("country shards = %v, want usa and deu", got)
+	}
+
+	deviceFiltered := req
+	deviceFiltered.Filters = []Filter{{Dimension: "device", Operator: OperatorEquals, Expression: "MOBILE"}}
+	if _, err := ShardRequest(ctx, q, deviceFiltered, ShardDevice); err == nil {
+		t.Error("sharding by device while filtering on device did not fail")
+	}
+	if _, err := ShardRequest(ctx, q, req, "page"); err == nil {
+		t.Error("an invalid shard mode did not fail")
+	}
+}
+
+func TestFetchShardsConcurrency(t *testing.T) {
+	var shards []Shard
+	for _, day := range []string{"01", "02", "03", "04", "05", "06", "07", "08", "09", "10"} {
+		date := "2026-03-" + day
+		shards = append(shards, Shard{Label: date, Request: QueryRequest{StartDate: date, EndDate: date}})
+	}
+
+	for _, concurrency := range []int{0, 1, 3} {
+		q := &shardQuerier{delay: 10 * time.Millisecond}
+		handled := 0
+		err := FetchShards(context.Background(), q, shards, concurrency, nil, func(Shard, *QueryResult) error {
+			handled++
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if handled != len(shards) {
+			t.Errorf("concurrency %d: handled %d shards, want %d", concurrency, handled, len(shards))
+		}
+
+		limit := max(concurrency, 1)
+		if q.maxInFlight > limit {
+			t.Errorf("concurrency %d: %d shards ran at once", concurrency, q.maxInFlight)
+		}
+		if limit > 1 && q.maxInFlight < 2 {
+			t.Errorf("concurrency %d: shards never ran at the same time", concurrency)
+		}
+	}
+}
+
+func TestQueryShardedMerge(t *testing.T) {
+	q := &shardQuerier{
+		rows: map[string][]QueryRow{
+			"DESKTOP": {
+				{Query: "a", Clicks: 10, Impressions: 100, CTR: 0.1, Position: 2},
+				{Query: "b", Clicks: 1, Impressions: 10, CTR: 0.1, Position: 5},
+			},
+			"MOBILE": {
+				{Query: "a", Clicks: 5, Impressions: 300, CTR: 5.0 / 300, Position: 6},
+				{Query: "c", Clicks: 0, Impressions: 50, Position: 9},
+			},
+		},
+		incomplete: map[string]string{"DESKTOP": "2026-03-05", "MOBILE": "2026-03-04"},
+	}
+	req := QueryRequest{StartDate: "2026-03-01", EndDate: "2026-03-05", SearchType: "web"}
+
+	result, err := QuerySharded(context.Background(), q, req, ShardDevice, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []QueryRow{
+		// Position is weighted by impressions: (2*100 + 6*300) / 400
+		{Query: "a", Clicks: 15, Impressions: 400, CTR: 15.0 / 400, Position: 5},
+		{Query: "b", Clicks: 1, Impressions: 10, CTR: 0.1, Position: 5},
+		{Query: "c", Clicks: 0, Impressions: 50, CTR: 0, Position: 9},
+	}
+	if len(result.Rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(result.Rows), len(want), result.Rows)
+	}
+	for i, w := range want {
+		g := result.Rows[i]
+		if g.Query != w.Query || g.Clicks != w.Clicks || g.Impressions != w.Impressions ||
+			math.Abs(g.CTR-w.CTR) > 1e-9 || math.Abs(g.Position-w.Position) > 1e-9 {
+			t.Errorf("row %d = %+v, want %+v", i, g, w)
+		}
+	}
+	if result.TotalRows != 3 {
+		t.Errorf("TotalRows = %d, want 3", result.TotalRows)
+	}
+	if result.FirstIncompleteDate != "2026-03-04" {
+		t.Errorf("FirstIncompleteDate = %q, want the earliest", result.FirstIncompleteDate)
+	}
+
+	req.RowLimit = 1
+	result, err = QuerySharded(context.Background(), q, req, ShardDevice, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0].Query != "a" || result.TotalRows != 3 {
+		t.Errorf("with a row limit got %+v (%d total), want only the top row of 3", result.Rows, result.TotalRows)
+	}
+}
+
+func TestQueryShardedPartialResult(t *testing.T) {
+	q := &shardQuerier{
+		rows: map[string][]QueryRow{
+			"2026-03-01": {{Query: "a", Clicks: 2, Impressions: 20, Position: 1}},
+			"2026-03-03": {{Query: "a", Clicks: 3, Impressions: 30, Position: 3}},
+		},
+		fail: "2026-03-02",
+	}
+	req := QueryRequest{StartDate: "2026-03-01", EndDate: "2026-03-03", SearchType: "web"}
+
+	// One shard at a time, so the shard after the failing one never runs
+	result, err := QuerySharded(context.Background(), q, req, ShardDay, 1, nil)
+	if err == nil || !strings.Contains(err.Error(), "2026-03-02") {
+		t.Fatalf("error = %v, want the failed shard", err)
+	}
+	if result == nil {
+		t.Fatal("got no partial result")
+	}
+	want := []QueryRow{{Query: "a", Clicks: 2, Impressions: 20, CTR: 0.1, Position: 1}}
+	if !reflect.DeepEqual(result.Rows, want) {
+		t.Errorf("partial rows = %+v, want %+v", result.Rows, want)
+	}
+	if slices.Contains(q.requests, "2026-03-03") {
+		t.Error("a shard was fetched after another failed")
+	}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sivori/gsc-cli/internal/config"
+
+	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Credential sources, in the order they are tried
+const (
+	SourceAccessToken    = "access token"
+	SourceServiceAccount = "service account"
+	SourceADC            = "application default credentials"
+	SourceOAuth          = "oauth login"
+)
+
+// ActiveSource returns where API credentials come from: an access token
+// given on the command line or in GSC_ACCESS_TOKEN, a service account key,
+// Application Default Credentials if the profile was set up with them, or
+// the OAuth login. With nothing configured, Application Default Credentials
+// are used only if GOOGLE_APPLICATION_CREDENTIALS names a key; gcloud logins
+// and the GCP metadata server need 'gsc auth login --adc'. Otherwise ""
+// is returned.
+func ActiveSource() string {
+	switch {
+	case config.GetAccessToken() != "":
+		return SourceAccessToken
+	case config.GetServiceAccountKey() != "":
+		return SourceServiceAccount
+	case config.GetUseADC():
+		return SourceADC
+	case config.GetClientSecretPath() != "":
+		return SourceOAuth
+	case os.Getenv(adcEnvVar) != "":
+		return SourceADC
+	}
+	return ""
+}
+
+// adcEnvVar names the key file Application Default Credentials read first
+const adcEnvVar = "GOOGLE_APPLICATION_CREDENTIALS"
+
+// AccessTokenSource returns the access token given on the command line or
+// in GSC_ACCESS_TOKEN. It is used as is and cannot be refreshed.
+func AccessTokenSource() oauth2.TokenSource {
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: config.GetAccessToken(), TokenType: "Bearer"})
+}
+
+// ADCTokenSource returns tokens from Application Default Credentials:
+// GOOGLE_APPLICATION_CREDENTIALS, 'gcloud auth application-default login',
+// or the metadata server on GCP. Tokens carry scopes, or read-only access if
+// none are given.
+func ADCTokenSource(ctx context.Context, scopes ...string) (oauth2.TokenSource, error) {
+	if len(scopes) == 0 {
+		scopes = []string{ScopeReadOnly}
+	}
+
+	creds, err := google.FindDefaultCredentials(ctx, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("could not find application default credentials: %w", err)
+	}
+	return creds.TokenSource, nil
+}
+
+// ADCAccount returns the email Application Default Credentials act as, or
+// "" if it cannot be told, e.g. for a gcloud user login
+func ADCAccount(ctx context.Context) string {
+	creds, err := google.FindDefaultCredentials(ctx, ScopeReadOnly)
+	if err != nil {
+		return ""
+	}
+
+	if len(creds.JSON) == 0 {
+		// Credentials from the GCP metadata server
+		email, _ := metadata.EmailWithContext(ctx, "default")
+		return email
+	}
+
+	var key struct {
+		ClientEmail string `json:"client_email"`
+	}
+	json.Unmarshal(creds.JSON, &key)
+	return key.ClientEmail
+}
@@ -0,0 +1,17 @@
+package auth
+
+import "testing"
+
+func TestActiveSourceADCFallback(t *testing.T) {
+	t.Setenv("GSC_ACCESS_TOKEN", "")
+
+	t.Setenv(adcEnvVar, "")
+	if got := ActiveSource(); got != "" {
+		t.Errorf("with nothing configured, ActiveSource() = %q, want none", got)
+	}
+
+	t.Setenv(adcEnvVar, "/path/to/key.json")
+	if got := ActiveSource(); got != SourceADC {
+		t.Errorf("with %s set, ActiveSource() = %q, want %q", adcEnvVar, got, SourceADC)
+	}
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sivori/gsc-cli/internal/config"
+
+	"golang.org/x/oauth2"
+)
+
+// envTokenVar is the environment variable holding the default profile's
+// token as JSON. Other profiles use GSC_TOKEN_<PROFILE>.
+const envTokenVar = "GSC_TOKEN"
+
+// envTokenName returns the environment variable for a profile. "-" maps to
+// "_", so "client-a" and "client_a" share a name.
+func envTokenName(profile string) string {
+	if profile == "" || profile == defaultProfile {
+		return envTokenVar
+	}
+	name := strings.ToUpper(strings.ReplaceAll(profile, "-", "_"))
+	return envTokenVar + "_" + name
+}
+
+// EnvTokenVar returns the environment variable the env store reads a
+// profile's token from. It fails if another configured profile maps to the
+// same variable, since either could then pick up the other's token.
+func EnvTokenVar(profile string) (string, error) {
+	name := envTokenName(profile)
+	for _, p := range config.Profiles() {
+		if p.Name != profile && envTokenName(p.Name) == name {
+			return "", fmt.Errorf("profiles %s and %s both read their token from %s; rename one of them to use the env store", profile, p.Name, name)
+		}
+	}
+	return name, nil
+}
+
+// envTokenSet reports whether the active profile's token is in the
+// environment. A variable shared with another profile still counts, so
+// the env store is picked and reports the conflict.
+func envTokenSet() bool {
+	return os.Getenv(envTokenName(config.Profile())) != ""
+}
+
+// envStore reads tokens from environment variables, for CI. It cannot
+// store tokens, so refreshed access tokens only live for the current run.
+type envStore struct{}
+
+// Name implements TokenStore
+func (envStore) Name() string {
+	return StoreEnv
+}
+
+// Get implements TokenStore
+func (envStore) Get(profile string) (*oauth2.Token, error) {
+	name, err := EnvTokenVar(profile)
+	if err != nil {
+		return nil, err
+	}
+	data := os.Getenv(name)
+	if data == "" {
+		return nil, nil
+	}
+
+	var token oauth2.Token
+	if err := json.Unmarshal([]byte(data), &token); err != nil {
+		return nil, fmt.Errorf("could not decode token from %s: %w", name, err)
+	}
+
+	return &token, nil
+}
+
+// Set implements TokenStore
+func (envStore) Set(profile string, token *oauth2.Token) error {
+	name, err := EnvTokenVar(profile)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("cannot save token: %w (set %s instead)", ErrReadOnly, name)
+}
+
+// Delete implements TokenStore
+func (envStore) Delete(profile string) error {
+	name, err := EnvTokenVar(profile)
+	if err != nil {
+		return err
+	}
+	if os.Getenv(name) == "" {
+		return nil
+	}
+	return fmt.Errorf("cannot delete token: %w (unset %s instead)", ErrReadOnly, name)
+}
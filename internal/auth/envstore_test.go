@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sivori/gsc-cli/internal/config"
+)
+
+func TestEnvTokenVar(t *testing.T) {
+	xdg := t.TempDir()
+	dir := filepath.Join(xdg, "gsc-cli")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	profiles := "profiles:\n  client-a:\n    site_url: a\n  client_a:\n    site_url: b\n  work-b:\n    site_url: c\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(profiles), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Chdir(t.TempDir())
+	if err := config.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		profile string
+		want    string
+		errText string
+	}{
+		{"default", "GSC_TOKEN", ""},
+		{"work-b", "GSC_TOKEN_WORK_B", ""},
+		{"client-a", "", "client_a"},
+		{"client_a", "", "client-a"},
+	}
+
+	for _, tt := range tests {
+		got, err := EnvTokenVar(tt.profile)
+		if tt.errText != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errText) {
+				t.Errorf("EnvTokenVar(%q) error = %v, want a conflict with %s", tt.profile, err, tt.errText)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("EnvTokenVar(%q) = %q, %v, want %q", tt.profile, got, err, tt.want)
+		}
+	}
+
+	// The conflict is reported when the token is read, not just ignored
+	t.Setenv("GSC_TOKEN_CLIENT_A", `{"access_token":"a"}`)
+	if _, err := (envStore{}).Get("client-a"); err == nil {
+		t.Error("Get() read a token shared by two profiles")
+	}
+}
@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/oauth2"
+	"golang.org/x/term"
+)
+
+// Environment variables holding the file store's secret. A key is 32 random
+// bytes in base64 (e.g. from 'openssl rand -base64 32'); a passphrase is
+// stretched with scrypt.
+const (
+	storeKeyVar        = "GSC_STORE_KEY"
+	storePassphraseVar = "GSC_STORE_PASSPHRASE"
+)
+
+// tokenFile is the on-disk format of the file store. Data is the
+// AES-256-GCM encrypted JSON map of profile to token.
+type tokenFile struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// fileStore keeps tokens in an encrypted file readable only by the user
+type fileStore struct {
+	path string
+
+	mu   sync.Mutex
+	salt []byte // salt the cached key was derived with
+	key  []byte
+}
+
+// Name implements TokenStore
+func (s *fileStore) Name() string {
+	return StoreFile
+}
+
+// Get implements TokenStore
+func (s *fileStore) Get(profile string) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, _, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return tokens[storeProfile(profile)], nil
+}
+
+// Set implements TokenStore
+func (s *fileStore) Set(profile string, token *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, salt, err := s.load()
+	if err != nil {
+		return err
+	}
+	tokens[storeProfile(profile)] = token
+	return s.save(tokens, salt)
+}
+
+// Delete implements TokenStore
+func (s *fileStore) Delete(profile string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return nil
+	}
+
+	tokens, salt, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[storeProfile(profile)]; !ok {
+		return nil
+	}
+	delete(tokens, storeProfile(profile))
+	return s.save(tokens, salt)
+}
+
+// load decrypts the token file. A missing file is an empty store with a
+// new salt.
+func (s *fileStore) load() (map[string]*oauth2.Token, []byte, error) {
+	tokens := make(map[string]*oauth2.Token)
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, nil, fmt.Errorf("could not generate salt: %w", err)
+		}
+		return tokens, salt, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read token file: %w", err)
+	}
+
+	var file tokenFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("could not decode token file %s: %w", s.path, err)
+	}
+
+	gcm, err := s.cipher(file.Salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(file.Nonce) != gcm.NonceSize() {
+		return nil, nil, fmt.Errorf("could not decode token file %s: bad nonce", s.path)
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not decrypt token file %s: wrong passphrase or %s", s.path, storeKeyVar)
+	}
+
+	if err := json.Unmarshal(plain, &tokens); err != nil {
+		return nil, nil, fmt.Errorf("could not decode token file %s: %w", s.path, err)
+	}
+	return tokens, file.Salt, nil
+}
+
+// save encrypts tokens and atomically replaces the token file
+func (s *fileStore) save(tokens map[string]*oauth2.Token, salt []byte) error {
+	plain, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("could not encode tokens: %w", err)
+	}
+
+	gcm, err := s.cipher(salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("could not generate nonce: %w", err)
+	}
+
+	data, err := json.Marshal(tokenFile{
+		Salt:  salt,
+		Nonce: nonce,
+		Data:  gcm.Seal(nil, nonce, plain, nil),
+	})
+	if err != nil {
+		return fmt.Errorf("could not encode token file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("could not create config directory: %w", err)
+	}
+
+	// CreateTemp makes the file 0600
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".tokens-*")
+	if err != nil {
+		return fmt.Errorf("could not write token file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write token file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write token file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("could not write token file: %w", err)
+	}
+
+	return nil
+}
+
+// cipher returns the AES-GCM cipher for the file's salt
+func (s *fileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	if s.key == nil || !bytes.Equal(s.salt, salt) {
+		_, statErr := os.Stat(s.path)
+		key, err := storeKey(salt, os.IsNotExist(statErr))
+		if err != nil {
+			return nil, err
+		}
+		s.key, s.salt = key, salt
+	}
+
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// storeKey returns the encryption key from GSC_STORE_KEY, or derives one
+// from GSC_STORE_PASSPHRASE. Without either, the passphrase is asked for on
+// the terminal, twice when it protects a new file.
+func storeKey(salt []byte, newFile bool) ([]byte, error) {
+	if encoded := os.Getenv(storeKeyVar); encoded != "" {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("%s must be 32 bytes in base64 (e.g. from 'openssl rand -base64 32')", storeKeyVar)
+		}
+		return key, nil
+	}
+
+	passphrase := os.Getenv(storePassphraseVar)
+	if passphrase == "" {
+		var err error
+		if passphrase, err = promptPassphrase(newFile); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("could not derive key: %w", err)
+	}
+	return key, nil
+}
+
+// promptPassphrase reads the file store passphrase from the terminal
+// without echoing it
+func promptPassphrase(confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("the file token store needs " + storePassphraseVar + " or " + storeKeyVar +
+			" to be set, or a terminal to ask for a passphrase")
+	}
+
+	fmt.Fprint(os.Stderr, "Token store passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("could not read passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return "", errors.New("no passphrase entered")
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("could not read passphrase: %w", err)
+		}
+		if !bytes.Equal(passphrase, again) {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+
+	return string(passphrase), nil
+}
+
+// storeProfile normalizes the default profile's name
+func storeProfile(profile string) string {
+	if profile == "" {
+		return defaultProfile
+	}
+	return profile
+}
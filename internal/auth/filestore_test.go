@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/term"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	t.Setenv(storeKeyVar, "")
+	t.Setenv(storePassphraseVar, "correct horse")
+	path := filepath.Join(t.TempDir(), "tokens.enc")
+
+	store := &fileStore{path: path}
+	if err := store.Set("work", &oauth2.Token{AccessToken: "a", RefreshToken: "r"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store must derive the same key from the passphrase
+	token, err := (&fileStore{path: path}).Get("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == nil || token.RefreshToken != "r" {
+		t.Fatalf("Get() = %+v, want the saved token", token)
+	}
+
+	t.Setenv(storePassphraseVar, "wrong")
+	if _, err := (&fileStore{path: path}).Get("work"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("Get() with the wrong passphrase error = %v", err)
+	}
+}
+
+func TestFileStoreNeedsSecret(t *testing.T) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		t.Skip("stdin is a terminal, so the passphrase would be asked for")
+	}
+	t.Setenv(storeKeyVar, "")
+	t.Setenv(storePassphraseVar, "")
+
+	err := (&fileStore{path: filepath.Join(t.TempDir(), "tokens.enc")}).Set("default", &oauth2.Token{AccessToken: "a"})
+	if err == nil || !strings.Contains(err.Error(), storePassphraseVar) {
+		t.Errorf("Set() without a secret error = %v, want it to name %s", err, storePassphraseVar)
+	}
+}
@@ -11,9 +11,37 @@ import (
 const serviceName = "gsc-cli"
 const tokenKey = "oauth_token"
 
-// GetToken retrieves the OAuth token from the OS keychain
-func GetToken() (*oauth2.Token, error) {
-	data, err := keyring.Get(serviceName, tokenKey)
+// defaultProfile keeps its token under the original key so existing
+// logins carry over
+const defaultProfile = "default"
+
+// profileKey returns the keyring key holding a profile's token
+func profileKey(profile string) string {
+	if profile == "" || profile == defaultProfile {
+		return tokenKey
+	}
+	return tokenKey + ":" + profile
+}
+
+// keyringAvailable reports whether the OS keychain can be reached. On
+// headless Linux there is often no Secret Service to talk to.
+func keyringAvailable() bool {
+	_, err := keyring.Get(serviceName, tokenKey)
+	return err == nil || err == keyring.ErrNotFound
+}
+
+// keyringStore keeps tokens in the OS keychain (macOS Keychain, Windows
+// Credential Manager, or Linux Secret Service)
+type keyringStore struct{}
+
+// Name implements TokenStore
+func (keyringStore) Name() string {
+	return StoreKeyring
+}
+
+// Get implements TokenStore
+func (keyringStore) Get(profile string) (*oauth2.Token, error) {
+	data, err := keyring.Get(serviceName, profileKey(profile))
 	if err != nil {
 		if err == keyring.ErrNotFound {
 			return nil, nil
@@ -29,23 +57,23 @@ func GetToken() (*oauth2.Token, error) {
 	return &token, nil
 }
 
-// SetToken stores the OAuth token in the OS keychain
-func SetToken(token *oauth2.Token) error {
+// Set implements TokenStore
+func (keyringStore) Set(profile string, token *oauth2.Token) error {
 	data, err := json.Marshal(token)
 	if err != nil {
 		return fmt.Errorf("could not encode token: %w", err)
 	}
 
-	if err := keyring.Set(serviceName, tokenKey, string(data)); err != nil {
+	if err := keyring.Set(serviceName, profileKey(profile), string(data)); err != nil {
 		return fmt.Errorf("could not store token in keyring: %w", err)
 	}
 
 	return nil
 }
 
-// DeleteToken removes the OAuth token from the keychain
-func DeleteToken() error {
-	if err := keyring.Delete(serviceName, tokenKey); err != nil {
+// Delete implements TokenStore
+func (keyringStore) Delete(profile string) error {
+	if err := keyring.Delete(serviceName, profileKey(profile)); err != nil {
 		if err == keyring.ErrNotFound {
 			return nil
 		}
@@ -53,9 +81,3 @@ func DeleteToken() error {
 	}
 	return nil
 }
-
-// HasToken checks if a token exists in the keychain
-func HasToken() bool {
-	token, _ := GetToken()
-	return token != nil
-}
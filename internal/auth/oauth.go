@@ -1,12 +1,22 @@
 package auth
 
 import (
+	"bufio"
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/browser"
@@ -14,19 +24,22 @@ import (
 	"golang.org/x/oauth2/google"
 )
 
-const (
-	// Search Console API scope
-	searchConsoleScope = "https://www.googleapis.com/auth/webmasters.readonly"
-)
+// revokeURL is Google's OAuth token revocation endpoint
+const revokeURL = "https://oauth2.googleapis.com/revoke"
 
-// LoadClientConfig loads OAuth2 config from client_secret.json
-func LoadClientConfig(clientSecretPath string) (*oauth2.Config, error) {
+// LoadClientConfig loads OAuth2 config from client_secret.json, asking for
+// scopes or DefaultScopes
+func LoadClientConfig(clientSecretPath string, scopes ...string) (*oauth2.Config, error) {
 	data, err := os.ReadFile(clientSecretPath)
 	if err != nil {
 		return nil, fmt.Errorf("could not read client secret file: %w", err)
 	}
 
-	config, err := google.ConfigFromJSON(data, searchConsoleScope)
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+
+	config, err := google.ConfigFromJSON(data, scopes...)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse client secret: %w", err)
 	}
@@ -34,9 +47,10 @@ func LoadClientConfig(clientSecretPath string) (*oauth2.Config, error) {
 	return config, nil
 }
 
-// LoginFlow performs the OAuth2 login flow with browser-based consent
-func LoginFlow(clientSecretPath string) (*oauth2.Token, error) {
-	config, err := LoadClientConfig(clientSecretPath)
+// LoginFlow performs the OAuth2 login flow with browser-based consent.
+// Instructions are written to out.
+func LoginFlow(ctx context.Context, clientSecretPath string, scopes []string, out io.Writer) (*oauth2.Token, error) {
+	config, err := LoadClientConfig(clientSecretPath, scopes...)
 	if err != nil {
 		return nil, err
 	}
@@ -51,74 +65,64 @@ func LoginFlow(clientSecretPath string) (*oauth2.Token, error) {
 	port := listener.Addr().(*net.TCPAddr).Port
 	config.RedirectURL = fmt.Sprintf("http://localhost:%d/callback", port)
 
-	// Channel to receive the auth code
-	codeChan := make(chan string, 1)
-	errChan := make(chan error, 1)
+	// A random state ties the callback to this login attempt, and PKCE
+	// ties the code exchange to it
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	// Start local server to handle callback, on its own mux so repeated
+	// logins in one process do not clash
+	callback := newCallbackHandler(state)
+	mux := http.NewServeMux()
+	mux.Handle("/callback", callback)
 
-	// Start local server to handle callback
 	server := &http.Server{
+		Handler:      mux,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-		code := r.URL.Query().Get("code")
-		if code == "" {
-			errMsg := r.URL.Query().Get("error")
-			if errMsg == "" {
-				errMsg = "no authorization code received"
-			}
-			errChan <- fmt.Errorf("authorization failed: %s", errMsg)
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(w, "<html><body><h1>Authorization Failed</h1><p>%s</p></body></html>", errMsg)
-			return
-		}
-
-		codeChan <- code
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, `<html><body>
-			<h1>Authorization Successful!</h1>
-			<p>You can close this window and return to the terminal.</p>
-			<script>window.close();</script>
-		</body></html>`)
-	})
-
+	errChan := make(chan error, 1)
 	go func() {
 		if err := server.Serve(listener); err != http.ErrServerClosed {
 			errChan <- fmt.Errorf("callback server error: %w", err)
 		}
 	}()
+	defer server.Shutdown(context.Background())
 
 	// Generate authorization URL and open browser
-	authURL := config.AuthCodeURL("state", oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, includeGrantedScopes, oauth2.S256ChallengeOption(verifier))
 
-	fmt.Println("Opening browser for authorization...")
-	fmt.Println("If the browser doesn't open, visit this URL:")
-	fmt.Println(authURL)
-	fmt.Println()
+	fmt.Fprintln(out, "Opening browser for authorization...")
+	fmt.Fprintln(out, "If the browser doesn't open, visit this URL:")
+	fmt.Fprintln(out, authURL)
+	fmt.Fprintln(out)
 
 	if err := browser.OpenURL(authURL); err != nil {
-		fmt.Printf("Could not open browser automatically: %v\n", err)
+		fmt.Fprintf(out, "Could not open browser automatically: %v\n", err)
 	}
 
 	// Wait for authorization code or error
 	var authCode string
 	select {
-	case authCode = <-codeChan:
-		// Success
+	case result := <-callback.result:
+		if result.err != nil {
+			return nil, result.err
+		}
+		authCode = result.code
 	case err := <-errChan:
-		server.Shutdown(context.Background())
 		return nil, err
 	case <-time.After(5 * time.Minute):
-		server.Shutdown(context.Background())
 		return nil, fmt.Errorf("authorization timed out after 5 minutes")
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 
-	server.Shutdown(context.Background())
-
 	// Exchange code for token
-	ctx := context.Background()
-	token, err := config.Exchange(ctx, authCode)
+	token, err := config.Exchange(ctx, authCode, oauth2.VerifierOption(verifier))
 	if err != nil {
 		return nil, fmt.Errorf("could not exchange authorization code: %w", err)
 	}
@@ -126,6 +130,177 @@ func LoginFlow(clientSecretPath string) (*oauth2.Token, error) {
 	return token, nil
 }
 
+// includeGrantedScopes keeps scopes granted earlier when asking for more
+var includeGrantedScopes = oauth2.SetAuthURLParam("include_granted_scopes", "true")
+
+// callbackResult is the outcome of an OAuth redirect
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// callbackHandler receives the OAuth redirect on the local callback server.
+// Only the first request carrying the expected state is accepted.
+type callbackHandler struct {
+	state  string
+	result chan callbackResult
+	once   sync.Once
+}
+
+// newCallbackHandler creates a handler expecting the given state
+func newCallbackHandler(state string) *callbackHandler {
+	return &callbackHandler{state: state, result: make(chan callbackResult, 1)}
+}
+
+// ServeHTTP implements http.Handler
+func (h *callbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// Requests without our state were not started by this login; reject
+	// them without ending the flow
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(h.state)) != 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "<html><body><h1>Authorization Failed</h1><p>Invalid state parameter.</p></body></html>")
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		errMsg := query.Get("error")
+		if errMsg == "" {
+			errMsg = "no authorization code received"
+		}
+		h.finish(callbackResult{err: fmt.Errorf("authorization failed: %s", errMsg)})
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "<html><body><h1>Authorization Failed</h1><p>%s</p></body></html>", html.EscapeString(errMsg))
+		return
+	}
+
+	h.finish(callbackResult{code: code})
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, `<html><body>
+		<h1>Authorization Successful!</h1>
+		<p>You can close this window and return to the terminal.</p>
+		<script>window.close();</script>
+	</body></html>`)
+}
+
+// finish reports the first result; later callbacks are ignored
+func (h *callbackHandler) finish(result callbackResult) {
+	h.once.Do(func() {
+		h.result <- result
+	})
+}
+
+// manualRedirectURL is where Google sends the browser in the no-browser flow.
+// Nothing listens there; the user copies the URL from the address bar.
+const manualRedirectURL = "http://localhost:8085/callback"
+
+// LoginFlowManual performs the OAuth2 login flow without a local browser:
+// the user opens the URL anywhere and pastes back the URL they were
+// redirected to, or just the code
+func LoginFlowManual(ctx context.Context, clientSecretPath string, scopes []string, in io.Reader, out io.Writer) (*oauth2.Token, error) {
+	config, err := LoadClientConfig(clientSecretPath, scopes...)
+	if err != nil {
+		return nil, err
+	}
+	config.RedirectURL = manualRedirectURL
+
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, includeGrantedScopes, oauth2.S256ChallengeOption(verifier))
+
+	fmt.Fprintln(out, "Open this URL in a browser on any machine and approve access:")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, authURL)
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "The browser is then sent to %s, which will fail to load.\n", manualRedirectURL)
+	fmt.Fprintln(out, "Copy the full URL from the address bar and paste it here.")
+	fmt.Fprint(out, "Redirect URL or code: ")
+
+	// Read in the background so Ctrl-C is not blocked on stdin
+	lineChan := make(chan string, 1)
+	errChan := make(chan error, 1)
+	go func() {
+		line, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && line == "" {
+			errChan <- fmt.Errorf("could not read input: %w", err)
+			return
+		}
+		lineChan <- line
+	}()
+
+	var input string
+	select {
+	case input = <-lineChan:
+	case err := <-errChan:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	code, err := parseAuthResponse(input, state)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("could not exchange authorization code: %w", err)
+	}
+
+	return token, nil
+}
+
+// parseAuthResponse extracts the authorization code from a pasted redirect
+// URL, checking its state, or accepts a bare code
+func parseAuthResponse(input, state string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", fmt.Errorf("no authorization code entered")
+	}
+
+	// A bare code has no query parameters
+	if !strings.Contains(input, "=") {
+		return input, nil
+	}
+
+	query := input
+	if i := strings.Index(input, "?"); i >= 0 {
+		query = input[i+1:]
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", fmt.Errorf("could not parse redirect URL: %w", err)
+	}
+
+	if errMsg := values.Get("error"); errMsg != "" {
+		return "", fmt.Errorf("authorization failed: %s", errMsg)
+	}
+	if subtle.ConstantTimeCompare([]byte(values.Get("state")), []byte(state)) != 1 {
+		return "", fmt.Errorf("state mismatch - the URL is not from this login attempt")
+	}
+
+	code := values.Get("code")
+	if code == "" {
+		return "", fmt.Errorf("no authorization code in redirect URL")
+	}
+	return code, nil
+}
+
+// randomString returns a URL-safe random string for OAuth state values
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate random state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // RefreshToken refreshes an expired token
 func RefreshToken(clientSecretPath string, token *oauth2.Token) (*oauth2.Token, error) {
 	config, err := LoadClientConfig(clientSecretPath)
@@ -143,9 +318,9 @@ func RefreshToken(clientSecretPath string, token *oauth2.Token) (*oauth2.Token,
 	return newToken, nil
 }
 
-// GetValidToken returns a valid token, refreshing if necessary
-func GetValidToken(clientSecretPath string) (*oauth2.Token, error) {
-	token, err := GetToken()
+// GetValidToken returns a profile's token, refreshing it if necessary
+func GetValidToken(profile, clientSecretPath string) (*oauth2.Token, error) {
+	token, err := GetToken(profile)
 	if err != nil {
 		return nil, err
 	}
@@ -159,8 +334,8 @@ func GetValidToken(clientSecretPath string) (*oauth2.Token, error) {
 		if err != nil {
 			return nil, err
 		}
-		// Save refreshed token
-		if err := SetToken(token); err != nil {
+		// Save refreshed token; a read-only store just refreshes again next run
+		if err := SetToken(profile, token); err != nil && !errors.Is(err, ErrReadOnly) {
 			return nil, err
 		}
 	}
@@ -168,6 +343,46 @@ func GetValidToken(clientSecretPath string) (*oauth2.Token, error) {
 	return token, nil
 }
 
+// RevokeToken revokes a token at Google. Revoking the refresh token also
+// revokes the access tokens issued from it.
+func RevokeToken(ctx context.Context, token *oauth2.Token) error {
+	value := token.RefreshToken
+	if value == "" {
+		value = token.AccessToken
+	}
+
+	form := url.Values{"token": {value}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("could not create revoke request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not revoke token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body)
+
+	// The token was already revoked or has expired
+	if body.Error == "invalid_token" {
+		return nil
+	}
+	if body.Error != "" {
+		return fmt.Errorf("could not revoke token: %s", body.Error)
+	}
+	return fmt.Errorf("could not revoke token: %s", resp.Status)
+}
+
 // TokenInfo returns basic info about the stored token
 type TokenInfo struct {
 	HasToken  bool
@@ -176,9 +391,9 @@ type TokenInfo struct {
 	TokenType string
 }
 
-// GetTokenInfo returns information about the stored token
-func GetTokenInfo() (*TokenInfo, error) {
-	token, err := GetToken()
+// GetTokenInfo returns information about a profile's stored token
+func GetTokenInfo(profile string) (*TokenInfo, error) {
+	token, err := GetToken(profile)
 	if err != nil {
 		return nil, err
 	}
@@ -195,6 +410,31 @@ func GetTokenInfo() (*TokenInfo, error) {
 	}, nil
 }
 
+// TokenEmail returns the account email from the ID token returned with a
+// freshly exchanged token, or "" if there is none. The ID token came
+// straight from Google's token endpoint over TLS, so its signature is not
+// checked.
+func TokenEmail(token *oauth2.Token) string {
+	idToken, _ := token.Extra("id_token").(string)
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	var claims struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	return claims.Email
+}
+
 // parseClientSecret extracts basic info from client_secret.json
 func parseClientSecret(path string) (projectID string, err error) {
 	data, err := os.ReadFile(path)
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCallbackHandler(t *testing.T) {
+	const state = "expected-state"
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		finished bool   // whether the login flow gets a result
+		code     string // the authorization code passed on
+		errText  string // part of the error passed on
+	}{
+		{"state mismatch", "?state=other&code=abc", http.StatusBadRequest, false, "", ""},
+		{"missing state", "?code=abc", http.StatusBadRequest, false, "", ""},
+		{"access denied", "?state=" + state + "&error=access_denied", http.StatusBadRequest, true, "", "access_denied"},
+		{"missing code", "?state=" + state, http.StatusBadRequest, true, "", "no authorization code received"},
+		{"success", "?state=" + state + "&code=abc", http.StatusOK, true, "abc", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newCallbackHandler(state)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/callback"+tt.query, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+
+			select {
+			case result := <-h.result:
+				if !tt.finished {
+					t.Fatalf("got result %+v, want none", result)
+				}
+				if result.code != tt.code {
+					t.Errorf("code = %q, want %q", result.code, tt.code)
+				}
+				switch {
+				case tt.errText == "" && result.err != nil:
+					t.Errorf("unexpected error: %v", result.err)
+				case tt.errText != "" && (result.err == nil || !strings.Contains(result.err.Error(), tt.errText)):
+					t.Errorf("error = %v, want it to mention %q", result.err, tt.errText)
+				}
+			default:
+				if tt.finished {
+					t.Fatal("got no result")
+				}
+			}
+		})
+	}
+}
+
+func TestCallbackHandlerKeepsFirstResult(t *testing.T) {
+	h := newCallbackHandler("s")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/callback?state=s&code=first", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/callback?state=s&code=second", nil))
+
+	if result := <-h.result; result.code != "first" {
+		t.Errorf("code = %q, want first", result.code)
+	}
+	select {
+	case result := <-h.result:
+		t.Errorf("got second result %+v", result)
+	default:
+	}
+}
+
+func TestCallbackHandlerEscapesError(t *testing.T) {
+	h := newCallbackHandler("s")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/callback?state=s&error=%3Cscript%3E", nil))
+
+	if strings.Contains(rec.Body.String(), "<script>") {
+		t.Errorf("error was not escaped: %s", rec.Body.String())
+	}
+}
+
+func TestParseAuthResponse(t *testing.T) {
+	const state = "expected-state"
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		errText string
+	}{
+		{"bare code", "4/0AbCd-ef_gh", "4/0AbCd-ef_gh", ""},
+		{"bare code with spaces", "  4/0AbCd\n", "4/0AbCd", ""},
+		{"redirect URL", "http://localhost:8085/callback?state=" + state + "&code=4/0Ab&scope=email", "4/0Ab", ""},
+		{"query only", "state=" + state + "&code=abc", "abc", ""},
+		{"empty", "   ", "", "no authorization code entered"},
+		{"access denied", "http://localhost:8085/callback?error=access_denied&state=" + state, "", "access_denied"},
+		{"state mismatch", "http://localhost:8085/callback?state=other&code=abc", "", "state mismatch"},
+		{"missing state", "http://localhost:8085/callback?code=abc", "", "state mismatch"},
+		{"missing code", "http://localhost:8085/callback?state=" + state, "", "no authorization code"},
+		{"bad query", "http://localhost:8085/callback?state=%zz", "", "could not parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAuthResponse(tt.input, state)
+			if tt.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("error = %v, want it to mention %q", err, tt.errText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("code = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
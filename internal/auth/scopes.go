@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sivori/gsc-cli/internal/config"
+
+	"golang.org/x/oauth2"
+)
+
+// Search Console API scopes
+const (
+	ScopeReadOnly  = "https://www.googleapis.com/auth/webmasters.readonly"
+	ScopeReadWrite = "https://www.googleapis.com/auth/webmasters"
+)
+
+// DefaultScopes are requested at login. openid and email return an ID
+// token naming the signed-in account.
+var DefaultScopes = []string{ScopeReadOnly, "openid", "email"}
+
+// impliedScopes lists the scopes each scope also grants
+var impliedScopes = map[string][]string{
+	ScopeReadWrite: {ScopeReadOnly},
+	"https://www.googleapis.com/auth/userinfo.email": {"email"},
+}
+
+// HasScopes reports whether granted covers every scope in needed
+func HasScopes(granted []string, needed ...string) bool {
+	for _, scope := range needed {
+		covered := slices.Contains(granted, scope)
+		for _, g := range granted {
+			covered = covered || slices.Contains(impliedScopes[g], scope)
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// GrantedScopes returns the scopes Google granted a freshly exchanged
+// token, or the requested ones if the response did not say
+func GrantedScopes(token *oauth2.Token, requested []string) []string {
+	if scope, _ := token.Extra("scope").(string); scope != "" {
+		return strings.Fields(scope)
+	}
+	return requested
+}
+
+// ProfileScopes returns the scopes the active profile's login was granted.
+// Logins from before scopes were recorded only asked for read-only access.
+func ProfileScopes() []string {
+	if scopes := config.GetScopes(); len(scopes) > 0 {
+		return scopes
+	}
+	return []string{ScopeReadOnly}
+}
+
+// ShortScope trims the common prefix off a scope for display
+func ShortScope(scope string) string {
+	return strings.TrimPrefix(scope, "https://www.googleapis.com/auth/")
+}
+
+// GrantScopes asks the user to approve additional scopes for the active
+// profile's OAuth login and stores the new token and scopes. Google adds the
+// new scopes to the ones already granted. With noBrowser, the consent URL is
+// printed and the redirect URL read from stdin, as with LoginFlowManual.
+func GrantScopes(ctx context.Context, clientSecretPath string, noBrowser bool, needed ...string) (*oauth2.Token, error) {
+	scopes := append(slices.Clone(DefaultScopes), needed...)
+
+	short := make([]string, len(needed))
+	for i, scope := range needed {
+		short[i] = ShortScope(scope)
+	}
+	fmt.Fprintf(os.Stderr, "This command needs additional access (%s). Approve it to continue.\n", strings.Join(short, ", "))
+
+	// stdout may carry command output, so prompt on stderr
+	var token *oauth2.Token
+	var err error
+	if noBrowser {
+		token, err = LoginFlowManual(ctx, clientSecretPath, scopes, os.Stdin, os.Stderr)
+	} else {
+		token, err = LoginFlow(ctx, clientSecretPath, scopes, os.Stderr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get additional access: %w", err)
+	}
+
+	if err := SetToken(config.Profile(), token); err != nil {
+		return nil, fmt.Errorf("could not save token: %w", err)
+	}
+	if err := config.SetScopes(GrantedScopes(token, scopes)); err != nil {
+		return nil, fmt.Errorf("could not save scopes: %w", err)
+	}
+
+	return token, nil
+}
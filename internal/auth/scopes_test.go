@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"slices"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestHasScopes(t *testing.T) {
+	email := "https://www.googleapis.com/auth/userinfo.email"
+	tests := []struct {
+		granted []string
+		needed  []string
+		want    bool
+	}{
+		{[]string{ScopeReadOnly}, []string{ScopeReadOnly}, true},
+		{[]string{ScopeReadOnly}, []string{ScopeReadWrite}, false},
+		{[]string{ScopeReadWrite}, []string{ScopeReadOnly}, true}, // read-write implies read-only
+		{[]string{ScopeReadWrite, email}, []string{ScopeReadOnly, "email"}, true},
+		{[]string{ScopeReadOnly, "openid"}, []string{ScopeReadOnly, "email"}, false},
+		{nil, []string{ScopeReadOnly}, false},
+		{nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		if got := HasScopes(tt.granted, tt.needed...); got != tt.want {
+			t.Errorf("HasScopes(%v, %v) = %v, want %v", tt.granted, tt.needed, got, tt.want)
+		}
+	}
+}
+
+func TestGrantedScopes(t *testing.T) {
+	requested := []string{ScopeReadOnly, "openid"}
+
+	token := (&oauth2.Token{AccessToken: "a"}).WithExtra(map[string]interface{}{
+		"scope": ScopeReadWrite + " openid  " + ScopeReadOnly,
+	})
+	if got, want := GrantedScopes(token, requested), []string{ScopeReadWrite, "openid", ScopeReadOnly}; !slices.Equal(got, want) {
+		t.Errorf("GrantedScopes() = %v, want %v", got, want)
+	}
+
+	// Without a scope in the response, the requested scopes are assumed
+	if got := GrantedScopes(&oauth2.Token{AccessToken: "a"}, requested); !slices.Equal(got, requested) {
+		t.Errorf("GrantedScopes() without scope = %v, want %v", got, requested)
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// ServiceAccount describes a service account JSON key
+type ServiceAccount struct {
+	Email     string
+	ProjectID string
+}
+
+// readServiceAccountKey reads and checks a service account JSON key
+func readServiceAccountKey(keyPath string) ([]byte, *ServiceAccount, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read service account key: %w", err)
+	}
+
+	var key struct {
+		Type        string `json:"type"`
+		ClientEmail string `json:"client_email"`
+		ProjectID   string `json:"project_id"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, nil, fmt.Errorf("could not parse service account key: %w", err)
+	}
+	if key.Type != "service_account" {
+		return nil, nil, fmt.Errorf("%s is not a service account key (type %q)", keyPath, key.Type)
+	}
+
+	return data, &ServiceAccount{Email: key.ClientEmail, ProjectID: key.ProjectID}, nil
+}
+
+// LoadServiceAccount returns the account a service account key belongs to
+func LoadServiceAccount(keyPath string) (*ServiceAccount, error) {
+	_, sa, err := readServiceAccountKey(keyPath)
+	return sa, err
+}
+
+// ServiceAccountTokenSource returns tokens for a service account key. With
+// a subject, the service account impersonates that user through domain-wide
+// delegation. Tokens carry scopes, or read-only access if none are given.
+func ServiceAccountTokenSource(ctx context.Context, keyPath, subject string, scopes ...string) (oauth2.TokenSource, error) {
+	data, _, err := readServiceAccountKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(scopes) == 0 {
+		scopes = []string{ScopeReadOnly}
+	}
+
+	jwtConfig, err := google.JWTConfigFromJSON(data, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse service account key: %w", err)
+	}
+	jwtConfig.Subject = subject
+
+	return jwtConfig.TokenSource(ctx), nil
+}
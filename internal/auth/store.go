@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sivori/gsc-cli/internal/config"
+
+	"golang.org/x/oauth2"
+)
+
+// Token store names
+const (
+	StoreAuto    = "auto"
+	StoreKeyring = "keyring"
+	StoreFile    = "file"
+	StoreEnv     = "env"
+)
+
+// StoreNames lists the token stores that can be selected
+var StoreNames = []string{StoreKeyring, StoreFile, StoreEnv}
+
+// ErrReadOnly is returned when writing to a store that cannot be written
+var ErrReadOnly = errors.New("token store is read-only")
+
+// TokenStore keeps one OAuth token per profile
+type TokenStore interface {
+	// Name returns the store's name, e.g. "keyring"
+	Name() string
+	// Get returns the profile's token, or nil if there is none
+	Get(profile string) (*oauth2.Token, error)
+	// Set stores the profile's token
+	Set(profile string, token *oauth2.Token) error
+	// Delete removes the profile's token; a missing token is not an error
+	Delete(profile string) error
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]TokenStore)
+)
+
+// OpenTokenStore returns the named token store. "auto" picks the env store
+// when GSC_TOKEN is set, the OS keychain when it is reachable, and the
+// encrypted file otherwise.
+func OpenTokenStore(name string) (TokenStore, error) {
+	if name == "" || name == StoreAuto {
+		switch {
+		case envTokenSet():
+			name = StoreEnv
+		case keyringAvailable():
+			name = StoreKeyring
+		default:
+			name = StoreFile
+		}
+	}
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, ok := stores[name]; ok {
+		return s, nil
+	}
+
+	var s TokenStore
+	switch name {
+	case StoreKeyring:
+		s = keyringStore{}
+	case StoreFile:
+		path, err := config.TokenFile()
+		if err != nil {
+			return nil, err
+		}
+		s = &fileStore{path: path}
+	case StoreEnv:
+		s = envStore{}
+	default:
+		return nil, fmt.Errorf("invalid token store: %s (valid: %s, %s)", name, StoreAuto, strings.Join(StoreNames, ", "))
+	}
+
+	stores[name] = s
+	return s, nil
+}
+
+// Store returns the token store selected by the token_store setting
+func Store() (TokenStore, error) {
+	return OpenTokenStore(config.GetTokenStore())
+}
+
+// GetToken retrieves a profile's OAuth token
+func GetToken(profile string) (*oauth2.Token, error) {
+	s, err := Store()
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(profile)
+}
+
+// SetToken stores a profile's OAuth token. The first time a token is saved
+// without a token_store setting, the store "auto" picked is saved as the
+// setting, so later reads look in the same place even if the keychain
+// stops being reachable.
+func SetToken(profile string, token *oauth2.Token) error {
+	s, err := Store()
+	if err != nil {
+		return err
+	}
+	if err := s.Set(profile, token); err != nil {
+		return err
+	}
+
+	if _, source := config.Get("token_store"); source == config.SourceDefault {
+		if err := config.SetTokenStore(s.Name()); err != nil {
+			return fmt.Errorf("could not save token store: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteToken removes a profile's OAuth token
+func DeleteToken(profile string) error {
+	s, err := Store()
+	if err != nil {
+		return err
+	}
+	return s.Delete(profile)
+}
+
+// HasToken checks if a profile has a stored token
+func HasToken(profile string) bool {
+	token, _ := GetToken(profile)
+	return token != nil
+}
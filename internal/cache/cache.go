@@ -0,0 +1,224 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sivori/gsc-cli/internal/config"
+)
+
+// Cache stores API responses on disk, one file per key. Entries record the
+// profile that wrote them so a profile's entries can be cleared on logout.
+type Cache struct {
+	dir     string
+	profile string
+}
+
+// entry is the on-disk form of a cached value
+type entry struct {
+	Key       string          `json:"key"`
+	Profile   string          `json:"profile,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt time.Time       `json:"expires_at,omitempty"` // zero means never
+	Value     json.RawMessage `json:"value"`
+}
+
+// expired reports whether the entry is past its TTL
+func (e *entry) expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)
+}
+
+// Open returns the cache in the default cache directory for the active
+// profile
+func Open() (*Cache, error) {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("could not create cache directory: %w", err)
+	}
+	return &Cache{dir: dir, profile: config.Profile()}, nil
+}
+
+// Dir returns the cache directory
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Profile returns the profile new entries are recorded for
+func (c *Cache) Profile() string {
+	return c.profile
+}
+
+// path returns the file for a key, named after its SHA-256 hash
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get decodes the cached value for key into v. It reports false if there
+// is no fresh entry.
+func (c *Cache) Get(key string, v interface{}) bool {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return false
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key || e.expired(time.Now()) {
+		return false
+	}
+
+	return json.Unmarshal(e.Value, v) == nil
+}
+
+// Put stores v under key. A zero ttl keeps the entry forever.
+func (c *Cache) Put(key string, v interface{}, ttl time.Duration) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("could not encode cache entry: %w", err)
+	}
+
+	e := entry{Key: key, Profile: c.profile, CreatedAt: time.Now(), Value: value}
+	if ttl > 0 {
+		e.ExpiresAt = e.CreatedAt.Add(ttl)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("could not encode cache entry: %w", err)
+	}
+
+	// Write to a temp file first so readers never see a partial entry
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Stats summarizes the cache contents
+type Stats struct {
+	Entries int
+	Expired int
+	Bytes   int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// Stats returns a summary of the cache contents
+func (c *Cache) Stats() (*Stats, error) {
+	st := &Stats{}
+	now := time.Now()
+
+	err := c.each(func(path string, info os.FileInfo) error {
+		st.Entries++
+		st.Bytes += info.Size()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			st.Expired++
+			return nil
+		}
+		if e.expired(now) {
+			st.Expired++
+		}
+		if st.Oldest.IsZero() || e.CreatedAt.Before(st.Oldest) {
+			st.Oldest = e.CreatedAt
+		}
+		if e.CreatedAt.After(st.Newest) {
+			st.Newest = e.CreatedAt
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Clear removes cache entries and returns how many were removed. With
+// expiredOnly, fresh entries are kept.
+func (c *Cache) Clear(expiredOnly bool) (int, error) {
+	now := time.Now()
+	return c.remove(func(e *entry) bool {
+		return !expiredOnly || e == nil || e.expired(now)
+	})
+}
+
+// ClearProfile removes the entries written for a profile and returns how
+// many were removed
+func (c *Cache) ClearProfile(profile string) (int, error) {
+	return c.remove(func(e *entry) bool {
+		return e != nil && e.Profile == profile
+	})
+}
+
+// remove deletes the entries match selects. Unreadable entries are passed
+// to match as nil.
+func (c *Cache) remove(match func(e *entry) bool) (int, error) {
+	removed := 0
+	err := c.each(func(path string, info os.FileInfo) error {
+		var e *entry
+		if data, err := os.ReadFile(path); err == nil {
+			e = &entry{}
+			if json.Unmarshal(data, e) != nil {
+				e = nil
+			}
+		}
+		if !match(e) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("could not remove cache entry: %w", err)
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// each calls fn for every entry file in the cache
+func (c *Cache) each(fn func(path string, info os.FileInfo) error) error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("could not read cache directory: %w", err)
+	}
+
+	for _, de := range entries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".json") {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		if err := fn(filepath.Join(c.dir, de.Name()), info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
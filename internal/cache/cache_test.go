@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestCache(t *testing.T, profile string) *Cache {
+	t.Helper()
+	return &Cache{dir: t.TempDir(), profile: profile}
+}
+
+func TestGetPut(t *testing.T) {
+	c := newTestCache(t, "default")
+
+	var got []string
+	if c.Get("k", &got) {
+		t.Fatal("Get() found an entry in an empty cache")
+	}
+	if err := c.Put("k", []string{"a", "b"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Get("k", &got) || len(got) != 2 || got[1] != "b" {
+		t.Errorf("Get() = %v, want [a b]", got)
+	}
+	if c.Get("other", &got) {
+		t.Error("Get() found an entry for another key")
+	}
+}
+
+func TestGetAfterExpiry(t *testing.T) {
+	c := newTestCache(t, "default")
+
+	if err := c.Put("short", 1, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("forever", 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	var v int
+	if c.Get("short", &v) {
+		t.Error("Get() returned an expired entry")
+	}
+	if !c.Get("forever", &v) || v != 2 {
+		t.Errorf("Get() = %d, want the entry without a TTL", v)
+	}
+}
+
+func TestGetChecksKey(t *testing.T) {
+	c := newTestCache(t, "default")
+
+	// An entry stored under one key's file but recording another key
+	data, _ := json.Marshal(entry{Key: "other", CreatedAt: time.Now(), Value: json.RawMessage("1")})
+	if err := os.WriteFile(c.path("k"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	if c.Get("k", &v) {
+		t.Error("Get() returned an entry recorded for a different key")
+	}
+}
+
+func TestClear(t *testing.T) {
+	c := newTestCache(t, "default")
+
+	c.Put("expired", 1, time.Millisecond)
+	c.Put("fresh", 2, time.Hour)
+	c.Put("forever", 3, 0)
+	if err := os.WriteFile(filepath.Join(c.dir, "broken.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	removed, err := c.Clear(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("Clear(true) removed %d entries, want the expired and the unreadable one", removed)
+	}
+	var v int
+	if !c.Get("fresh", &v) || !c.Get("forever", &v) {
+		t.Error("Clear(true) removed a fresh entry")
+	}
+
+	if removed, err = c.Clear(false); err != nil || removed != 2 {
+		t.Errorf("Clear(false) = %d, %v, want 2 removed", removed, err)
+	}
+}
+
+func TestClearProfile(t *testing.T) {
+	dir := t.TempDir()
+	work := &Cache{dir: dir, profile: "work"}
+	home := &Cache{dir: dir, profile: "home"}
+
+	work.Put("a", 1, 0)
+	work.Put("b", 2, 0)
+	home.Put("c", 3, 0)
+
+	removed, err := home.ClearProfile("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("ClearProfile() removed %d entries, want 2", removed)
+	}
+	var v int
+	if !home.Get("c", &v) {
+		t.Error("ClearProfile() removed another profile's entry")
+	}
+}
+
+func TestPutIsAtomic(t *testing.T) {
+	c := newTestCache(t, "default")
+	small := []int{1}
+	large := make([]int, 50000)
+	for i := range large {
+		large[i] = i
+	}
+
+	// Readers racing writers see one whole value or the other, never a
+	// partial file
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := c.Put("k", large, 0); err != nil {
+					t.Error(err)
+				}
+				if err := c.Put("k", small, 0); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var got []int
+				if _, err := os.Stat(c.path("k")); err == nil && !c.Get("k", &got) {
+					t.Error("Get() could not read an entry being replaced")
+					return
+				}
+				if len(got) != 0 && len(got) != len(small) && len(got) != len(large) {
+					t.Errorf("Get() returned %d values", len(got))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// No temp files are left behind, and entries are only the final files
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), ".tmp-") {
+			t.Errorf("temp file %s left behind", f.Name())
+		}
+	}
+	if len(files) != 1 {
+		t.Errorf("cache holds %d files, want 1", len(files))
+	}
+}
+
+func TestTempFilesAreNotEntries(t *testing.T) {
+	c := newTestCache(t, "default")
+
+	c.Put("k", 1, 0)
+	if err := os.WriteFile(filepath.Join(c.dir, ".tmp-123"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Entries != 1 || st.Expired != 0 {
+		t.Errorf("Stats() = %d entries, %d expired, want 1 and 0", st.Entries, st.Expired)
+	}
+}
@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sivori/gsc-cli/internal/api"
+)
+
+const (
+	// settleDays is how long before a day's final data is treated as
+	// settled and cached indefinitely
+	settleDays = 7
+	// recentTTL applies to ranges that include recent days
+	recentTTL = time.Hour
+	// freshTTL applies to fresh (partial) data and freshness probes
+	freshTTL = 15 * time.Minute
+)
+
+// Querier answers queries from the cache, falling back to another Querier
+// and caching what it returns. Entries are keyed by the cache's profile and
+// the account, so profiles and accounts with access to the same site never
+// share results.
+type Querier struct {
+	q       api.Querier
+	cache   *Cache
+	siteURL string
+	account string
+}
+
+var _ api.Querier = (*Querier)(nil)
+
+// Wrap returns a Querier that caches the results q returns for a site when
+// signed in as account (an email, or the credential source if unknown)
+func Wrap(q api.Querier, c *Cache, siteURL, account string) *Querier {
+	return &Querier{q: q, cache: c, siteURL: siteURL, account: account}
+}
+
+// Query returns a cached result or runs the query
+func (c *Querier) Query(ctx context.Context, req api.QueryRequest) (*api.QueryResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	key := c.requestKey("query", req)
+	var cached api.QueryResult
+	if c.cache.Get(key, &cached) {
+		return &cached, nil
+	}
+
+	result, err := c.q.Query(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.Put(key, result, ttlFor(req))
+	return result, nil
+}
+
+// QueryAll returns a cached result or fetches every row
+func (c *Querier) QueryAll(ctx context.Context, req api.QueryRequest) (*api.QueryResult, error) {
+	return c.QueryAllWithProgress(ctx, req, nil)
+}
+
+// QueryAllWithProgress returns a cached result or fetches every row.
+// Incomplete results are not cached.
+func (c *Querier) QueryAllWithProgress(ctx context.Context, req api.QueryRequest, progress api.ProgressFunc) (*api.QueryResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	req.RowLimit, req.StartRow = 0, 0
+
+	key := c.requestKey("all", req)
+	var cached api.QueryResult
+	if c.cache.Get(key, &cached) {
+		if progress != nil {
+			progress(len(cached.Rows))
+		}
+		return &cached, nil
+	}
+
+	result, err := c.q.QueryAllWithProgress(ctx, req, progress)
+	if err != nil {
+		return result, err
+	}
+	c.cache.Put(key, result, ttlFor(req))
+	return result, nil
+}
+
+// Rows streams rows without caching, so exports stay in constant memory
+func (c *Querier) Rows(ctx context.Context, req api.QueryRequest, progress api.ProgressFunc) iter.Seq2[api.QueryRow, error] {
+	return c.q.Rows(ctx, req, progress)
+}
+
+// ProbeFreshness returns a recent cached probe or probes again
+func (c *Querier) ProbeFreshness(ctx context.Context, searchType string, includeFresh bool) (*api.Freshness, error) {
+	key, _ := json.Marshal(struct {
+		Kind         string `json:"kind"`
+		Profile      string `json:"profile"`
+		Account      string `json:"account"`
+		Site         string `json:"site"`
+		SearchType   string `json:"type"`
+		IncludeFresh bool   `json:"fresh"`
+	}{"freshness", c.cache.profile, c.account, c.siteURL, searchType, includeFresh})
+
+	var cached api.Freshness
+	if c.cache.Get(string(key), &cached) {
+		return &cached, nil
+	}
+
+	f, err := c.q.ProbeFreshness(ctx, searchType, includeFresh)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.Put(string(key), f, freshTTL)
+	return f, nil
+}
+
+// requestKey builds a canonical key for a validated request, so requests
+// that only differ in filter order or defaults share an entry
+func (c *Querier) requestKey(kind string, req api.QueryRequest) string {
+	dimensions := req.Dimensions
+	if len(dimensions) == 0 {
+		dimensions = []string{"query"}
+	}
+
+	filters := append([]api.Filter(nil), req.Filters...)
+	sort.Slice(filters, func(i, j int) bool {
+		a, b := filters[i], filters[j]
+		if a.Dimension != b.Dimension {
+			return a.Dimension < b.Dimension
+		}
+		if a.Operator != b.Operator {
+			return a.Operator < b.Operator
+		}
+		return a.Expression < b.Expression
+	})
+
+	dataState := req.DataState
+	if dataState == "" {
+		dataState = api.DataStateFinal
+	}
+
+	rowLimit := req.RowLimit
+	if kind == "query" && rowLimit == 0 {
+		rowLimit = 1000
+	}
+
+	key, _ := json.Marshal(struct {
+		Kind       string       `json:"kind"`
+		Profile    string       `json:"profile"`
+		Account    string       `json:"account"`
+		Site       string       `json:"site"`
+		StartDate  string       `json:"start"`
+		EndDate    string       `json:"end"`
+		Dimensions string       `json:"dimensions"`
+		Filters    []api.Filter `json:"filters"`
+		SearchType string       `json:"type"`
+		DataState  string       `json:"data_state"`
+		RowLimit   int64        `json:"row_limit"`
+		StartRow   int64        `json:"start_row"`
+	}{kind, c.cache.profile, c.account, c.siteURL, req.StartDate, req.EndDate, strings.ToLower(strings.Join(dimensions, ",")),
+		filters, req.SearchType, dataState, rowLimit, req.StartRow})
+	return string(key)
+}
+
+// ttlFor returns how long a result stays valid. Final data for days that
+// have settled never changes, so it is kept indefinitely.
+func ttlFor(req api.QueryRequest) time.Duration {
+	if req.DataState == api.DataStateAll {
+		return freshTTL
+	}
+	settled := time.Now().AddDate(0, 0, -settleDays).Format("2006-01-02")
+	if req.EndDate < settled {
+		return 0
+	}
+	return recentTTL
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/sivori/gsc-cli/internal/api"
+)
+
+func TestTTLFor(t *testing.T) {
+	day := func(offset int) string {
+		return time.Now().AddDate(0, 0, offset).Format("2006-01-02")
+	}
+
+	tests := []struct {
+		name string
+		req  api.QueryRequest
+		want time.Duration
+	}{
+		{"settled", api.QueryRequest{EndDate: day(-settleDays - 1)}, 0},
+		{"first unsettled day", api.QueryRequest{EndDate: day(-settleDays)}, recentTTL},
+		{"recent", api.QueryRequest{EndDate: day(-2)}, recentTTL},
+		{"fresh", api.QueryRequest{EndDate: day(-settleDays - 30), DataState: api.DataStateAll}, freshTTL},
+		{"fresh recent", api.QueryRequest{EndDate: day(0), DataState: api.DataStateAll}, freshTTL},
+	}
+
+	for _, tt := range tests {
+		if got := ttlFor(tt.req); got != tt.want {
+			t.Errorf("%s: ttlFor(end %s) = %v, want %v", tt.name, tt.req.EndDate, got, tt.want)
+		}
+	}
+}
+
+func TestRequestKeyIsCanonical(t *testing.T) {
+	q := Wrap(nil, &Cache{profile: "default"}, "sc-domain:example.com", "me@example.com")
+	base := api.QueryRequest{
+		StartDate:  "2026-01-01",
+		EndDate:    "2026-01-31",
+		Dimensions: []string{"query"},
+		Filters: []api.Filter{
+			{Dimension: "query", Operator: api.OperatorContains, Expression: "shoes"},
+			{Dimension: "device", Operator: api.OperatorEquals, Expression: "MOBILE"},
+		},
+		SearchType: "web",
+		RowLimit:   1000,
+	}
+	key := q.requestKey("query", base)
+
+	same := map[string]func(r *api.QueryRequest){
+		"filter order": func(r *api.QueryRequest) {
+			r.Filters = []api.Filter{r.Filters[1], r.Filters[0]}
+		},
+		"default dimensions": func(r *api.QueryRequest) { r.Dimensions = nil },
+		"dimension case":     func(r *api.QueryRequest) { r.Dimensions = []string{"Query"} },
+		"default row limit":  func(r *api.QueryRequest) { r.RowLimit = 0 },
+		"default data state": func(r *api.QueryRequest) { r.DataState = api.DataStateFinal },
+	}
+	for name, change := range same {
+		req := base
+		change(&req)
+		if got := q.requestKey("query", req); got != key {
+			t.Errorf("%s: key changed:\n got %s\nwant %s", name, got, key)
+		}
+	}
+
+	different := map[string]func(r *api.QueryRequest){
+		"dates":      func(r *api.QueryRequest) { r.EndDate = "2026-02-01" },
+		"dimensions": func(r *api.QueryRequest) { r.Dimensions = []string{"query", "page"} },
+		"filter":     func(r *api.QueryRequest) { r.Filters = r.Filters[:1] },
+		"data state": func(r *api.QueryRequest) { r.DataState = api.DataStateAll },
+		"row limit":  func(r *api.QueryRequest) { r.RowLimit = 10 },
+		"start row":  func(r *api.QueryRequest) { r.StartRow = 1000 },
+		"type":       func(r *api.QueryRequest) { r.SearchType = "image" },
+	}
+	for name, change := range different {
+		req := base
+		change(&req)
+		if q.requestKey("query", req) == key {
+			t.Errorf("%s: key did not change", name)
+		}
+	}
+
+	if q.requestKey("all", base) == key {
+		t.Error("kind is not part of the key")
+	}
+	for name, other := range map[string]*Querier{
+		"site":    Wrap(nil, &Cache{profile: "default"}, "sc-domain:other.com", "me@example.com"),
+		"profile": Wrap(nil, &Cache{profile: "work"}, "sc-domain:example.com", "me@example.com"),
+		"account": Wrap(nil, &Cache{profile: "default"}, "sc-domain:example.com", "you@example.com"),
+	} {
+		if other.requestKey("query", base) == key {
+			t.Errorf("%s is not part of the key", name)
+		}
+	}
+}
+
+// countingQuerier counts the queries that reach it
+type countingQuerier struct {
+	queries int
+	probes  int
+}
+
+func (q *countingQuerier) Query(ctx context.Context, req api.QueryRequest) (*api.QueryResult, error) {
+	q.queries++
+	return &api.QueryResult{Rows: []api.QueryRow{{Query: "a", Clicks: 1}}}, nil
+}
+
+func (q *countingQuerier) QueryAll(ctx context.Context, req api.QueryRequest) (*api.QueryResult, error) {
+	return q.Query(ctx, req)
+}
+
+func (q *countingQuerier) QueryAllWithProgress(ctx context.Context, req api.QueryRequest, progress api.ProgressFunc) (*api.QueryResult, error) {
+	return q.Query(ctx, req)
+}
+
+func (q *countingQuerier) Rows(ctx context.Context, req api.QueryRequest, progress api.ProgressFunc) iter.Seq2[api.QueryRow, error] {
+	return func(yield func(api.QueryRow, error) bool) {}
+}
+
+func (q *countingQuerier) ProbeFreshness(ctx context.Context, searchType string, includeFresh bool) (*api.Freshness, error) {
+	q.probes++
+	return &api.Freshness{}, nil
+}
+
+func TestQuerierSeparatesAccounts(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t, "default")
+	backend := &countingQuerier{}
+	req := api.QueryRequest{StartDate: "2026-01-01", EndDate: "2026-01-31"}
+
+	me := Wrap(backend, c, "sc-domain:example.com", "me@example.com")
+	for i := 0; i < 2; i++ {
+		result, err := me.Query(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Rows) != 1 {
+			t.Fatalf("Query() returned %d rows, want 1", len(result.Rows))
+		}
+		if _, err := me.ProbeFreshness(ctx, "web", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backend.queries != 1 || backend.probes != 1 {
+		t.Errorf("backend got %d queries and %d probes, want 1 of each", backend.queries, backend.probes)
+	}
+
+	you := Wrap(backend, c, "sc-domain:example.com", "you@example.com")
+	you.Query(ctx, req)
+	you.ProbeFreshness(ctx, "web", false)
+	if backend.queries != 2 || backend.probes != 2 {
+		t.Errorf("another account reused cached results (%d queries, %d probes)", backend.queries, backend.probes)
+	}
+}
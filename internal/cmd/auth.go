@@ -2,15 +2,22 @@ package cmd
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/sivori/gsc-cli/internal/auth"
+	"github.com/sivori/gsc-cli/internal/cache"
 	"github.com/sivori/gsc-cli/internal/config"
+	"github.com/sivori/gsc-cli/internal/output"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
 )
 
 func newAuthCmd() *cobra.Command {
@@ -23,6 +30,8 @@ func newAuthCmd() *cobra.Command {
 	cmd.AddCommand(newAuthLoginCmd())
 	cmd.AddCommand(newAuthLogoutCmd())
 	cmd.AddCommand(newAuthStatusCmd())
+	cmd.AddCommand(newAuthListCmd())
+	cmd.AddCommand(newAuthMigrateCmd())
 
 	return cmd
 }
@@ -30,6 +39,10 @@ func newAuthCmd() *cobra.Command {
 func newAuthLoginCmd() *cobra.Command {
 	var clientSecretPath string
 	var site string
+	var serviceAccountKey string
+	var subject string
+	var noBrowser bool
+	var useADC bool
 
 	cmd := &cobra.Command{
 		Use:   "login",
@@ -42,13 +55,51 @@ You need to:
 3. Create OAuth2 Desktop credentials
 4. Download the client_secret.json file
 
-Then run: gsc auth login --client-secret /path/to/client_secret.json`,
+Then run: gsc auth login --client-secret /path/to/client_secret.json
+
+On a machine without a browser (e.g. over SSH), add --no-browser: open the
+printed URL anywhere, then paste the URL you are redirected to back into the
+terminal.
+
+For CI and servers, authenticate with a service account key instead. Add the
+service account's email as a user of the property in Search Console first.
+
+  gsc auth login --service-account key.json --site sc-domain:example.com
+  gsc auth login --service-account key.json --subject user@example.com  # Domain-wide delegation
+
+Setting GSC_SERVICE_ACCOUNT_KEY (and optionally GSC_SERVICE_ACCOUNT_SUBJECT)
+selects a service account without logging in at all.
+
+On GCP, or after 'gcloud auth application-default login', use Application
+Default Credentials. With nothing else configured, they are also used when
+GOOGLE_APPLICATION_CREDENTIALS is set.
+
+  gsc auth login --adc --site sc-domain:example.com
+
+A short-lived access token from elsewhere can be passed with GSC_ACCESS_TOKEN
+or --access-token on any command.
+
+To use several Google accounts, log in to each under its own profile and
+pick one with --profile on any command:
+
+  gsc auth login --profile client-a --site sc-domain:client-a.com
+  gsc queries --profile client-a`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Initialize config
 			if err := config.Init(); err != nil {
 				return fmt.Errorf("could not initialize config: %w", err)
 			}
 
+			if useADC {
+				return loginADC(cmd.Context(), site)
+			}
+			if serviceAccountKey != "" {
+				return loginServiceAccount(cmd.Context(), serviceAccountKey, subject, site)
+			}
+			if subject != "" {
+				return fmt.Errorf("--subject requires --service-account")
+			}
+
 			// Get client secret path
 			if clientSecretPath == "" {
 				clientSecretPath = config.GetClientSecretPath()
@@ -69,40 +120,42 @@ Then run: gsc auth login --client-secret /path/to/client_secret.json`,
 				return fmt.Errorf("client secret file not found: %s", clientSecretPath)
 			}
 
-			// Get site URL
-			if site == "" {
-				site = config.GetSiteURL()
+			// Relative paths in the config file are relative to the file
+			var err error
+			if clientSecretPath, err = filepath.Abs(clientSecretPath); err != nil {
+				return fmt.Errorf("could not resolve client secret path: %w", err)
 			}
 
-			if site == "" {
-				fmt.Println()
-				fmt.Println("Enter your Search Console site URL.")
-				fmt.Println("Examples:")
-				fmt.Println("  - sc-domain:example.com (domain property)")
-				fmt.Println("  - https://example.com/ (URL prefix property)")
-				fmt.Print("Site URL: ")
-				reader := bufio.NewReader(os.Stdin)
-				input, err := reader.ReadString('\n')
-				if err != nil {
-					return fmt.Errorf("could not read input: %w", err)
-				}
-				site = strings.TrimSpace(input)
+			if site, err = promptSite(site); err != nil {
+				return err
 			}
 
 			fmt.Println()
 
 			// Perform OAuth login flow
-			token, err := auth.LoginFlow(clientSecretPath)
+			var token *oauth2.Token
+			if noBrowser {
+				token, err = auth.LoginFlowManual(cmd.Context(), clientSecretPath, auth.DefaultScopes, os.Stdin, os.Stdout)
+			} else {
+				token, err = auth.LoginFlow(cmd.Context(), clientSecretPath, auth.DefaultScopes, os.Stdout)
+			}
 			if err != nil {
 				return fmt.Errorf("authentication failed: %w", err)
 			}
 
 			// Store token
-			if err := auth.SetToken(token); err != nil {
+			if err := auth.SetToken(config.Profile(), token); err != nil {
 				return fmt.Errorf("could not save token: %w", err)
 			}
+			account := auth.TokenEmail(token)
 
-			// Save config
+			// Save config, switching away from any service account or ADC
+			if err := config.SetServiceAccount("", ""); err != nil {
+				return fmt.Errorf("could not save config: %w", err)
+			}
+			if err := config.SetUseADC(false); err != nil {
+				return fmt.Errorf("could not save config: %w", err)
+			}
 			if err := config.SetClientSecretPath(clientSecretPath); err != nil {
 				return fmt.Errorf("could not save client secret path: %w", err)
 			}
@@ -110,9 +163,22 @@ Then run: gsc auth login --client-secret /path/to/client_secret.json`,
 			if err := config.SetSiteURL(site); err != nil {
 				return fmt.Errorf("could not save site URL: %w", err)
 			}
+			if err := config.SetAccount(account); err != nil {
+				return fmt.Errorf("could not save account: %w", err)
+			}
+			if err := config.SetScopes(auth.GrantedScopes(token, auth.DefaultScopes)); err != nil {
+				return fmt.Errorf("could not save scopes: %w", err)
+			}
+			if err := config.SetNoBrowser(noBrowser); err != nil {
+				return fmt.Errorf("could not save config: %w", err)
+			}
 
 			green := color.New(color.FgGreen).SprintFunc()
 			fmt.Printf("%s Successfully authenticated!\n", green("✓"))
+			if account != "" {
+				fmt.Printf("  Account: %s\n", account)
+			}
+			printProfile()
 			fmt.Printf("  Site: %s\n", site)
 
 			return nil
@@ -121,25 +187,207 @@ Then run: gsc auth login --client-secret /path/to/client_secret.json`,
 
 	cmd.Flags().StringVar(&clientSecretPath, "client-secret", "", "Path to client_secret.json")
 	cmd.Flags().StringVar(&site, "site", "", "Search Console site URL")
+	cmd.Flags().BoolVar(&noBrowser, "no-browser", false, "Print the login URL and paste back the redirect URL (for SSH sessions)")
+	cmd.Flags().StringVar(&serviceAccountKey, "service-account", "", "Path to a service account JSON key (no browser needed)")
+	cmd.Flags().StringVar(&subject, "subject", "", "User to impersonate with domain-wide delegation (with --service-account)")
+	cmd.Flags().BoolVar(&useADC, "adc", false, "Use Application Default Credentials (GCP, gcloud)")
+	cmd.MarkFlagsMutuallyExclusive("adc", "service-account")
 
 	return cmd
 }
 
+// promptSite returns site, or the configured site, or asks for one
+func promptSite(site string) (string, error) {
+	if site == "" {
+		site = config.GetSiteURL()
+	}
+	if site != "" {
+		return site, nil
+	}
+
+	fmt.Println()
+	fmt.Println("Enter your Search Console site URL.")
+	fmt.Println("Examples:")
+	fmt.Println("  - sc-domain:example.com (domain property)")
+	fmt.Println("  - https://example.com/ (URL prefix property)")
+	fmt.Print("Site URL: ")
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("could not read input: %w", err)
+	}
+	return strings.TrimSpace(input), nil
+}
+
+// loginServiceAccount checks a service account key can get a token and
+// saves it as the credentials to use
+func loginServiceAccount(ctx context.Context, keyPath, subject, site string) error {
+	keyPath, err := filepath.Abs(keyPath)
+	if err != nil {
+		return fmt.Errorf("could not resolve key path: %w", err)
+	}
+
+	sa, err := auth.LoadServiceAccount(keyPath)
+	if err != nil {
+		return err
+	}
+
+	ts, err := auth.ServiceAccountTokenSource(ctx, keyPath, subject)
+	if err != nil {
+		return err
+	}
+	if _, err := ts.Token(); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+
+	site, err = promptSite(site)
+	if err != nil {
+		return err
+	}
+
+	if err := config.SetServiceAccount(keyPath, subject); err != nil {
+		return fmt.Errorf("could not save service account: %w", err)
+	}
+	if err := config.SetUseADC(false); err != nil {
+		return fmt.Errorf("could not save config: %w", err)
+	}
+	if err := config.SetSiteURL(site); err != nil {
+		return fmt.Errorf("could not save site URL: %w", err)
+	}
+	if err := config.SetAccount(sa.Email); err != nil {
+		return fmt.Errorf("could not save account: %w", err)
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Authenticated as service account %s\n", green("✓"), sa.Email)
+	if subject != "" {
+		fmt.Printf("  Acting as: %s\n", subject)
+	}
+	printProfile()
+	fmt.Printf("  Site: %s\n", site)
+
+	return nil
+}
+
+// loginADC checks Application Default Credentials can get a token and saves
+// them as the credentials to use
+func loginADC(ctx context.Context, site string) error {
+	ts, err := auth.ADCTokenSource(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := ts.Token(); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+
+	if site, err = promptSite(site); err != nil {
+		return err
+	}
+
+	if err := config.SetServiceAccount("", ""); err != nil {
+		return fmt.Errorf("could not save config: %w", err)
+	}
+	if err := config.SetUseADC(true); err != nil {
+		return fmt.Errorf("could not save config: %w", err)
+	}
+	if err := config.SetSiteURL(site); err != nil {
+		return fmt.Errorf("could not save site URL: %w", err)
+	}
+	account := auth.ADCAccount(ctx)
+	if err := config.SetAccount(account); err != nil {
+		return fmt.Errorf("could not save account: %w", err)
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Using application default credentials\n", green("✓"))
+	if account != "" {
+		fmt.Printf("  Account: %s\n", account)
+	}
+	printProfile()
+	fmt.Printf("  Site: %s\n", site)
+
+	return nil
+}
+
 func newAuthLogoutCmd() *cobra.Command {
-	return &cobra.Command{
+	var localOnly bool
+
+	cmd := &cobra.Command{
 		Use:   "logout",
-		Short: "Remove stored credentials",
+		Short: "Revoke and remove stored credentials",
+		Long: `Revoke the stored OAuth token at Google and remove it from this machine.
+
+Revoking ends gsc's access for the whole Google login, so other machines
+logged in as the same account with the same client need to log in again.
+Use --local-only to just forget the token here. Responses cached for the
+profile are removed either way.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := auth.DeleteToken(); err != nil {
+			if err := config.Init(); err != nil {
+				return fmt.Errorf("could not initialize config: %w", err)
+			}
+
+			revoked := false
+			if !localOnly && config.GetServiceAccountKey() == "" && !config.GetUseADC() {
+				token, err := auth.GetToken(config.Profile())
+				if err != nil {
+					return fmt.Errorf("could not read token: %w", err)
+				}
+				if token != nil {
+					if err := auth.RevokeToken(cmd.Context(), token); err != nil {
+						return fmt.Errorf("%w (use --local-only to just remove it from this machine)", err)
+					}
+					revoked = true
+				}
+			}
+
+			if err := auth.DeleteToken(config.Profile()); err != nil {
 				return fmt.Errorf("could not delete token: %w", err)
 			}
+			if config.GetServiceAccountKey() != "" {
+				if err := config.SetServiceAccount("", ""); err != nil {
+					return fmt.Errorf("could not remove service account: %w", err)
+				}
+			}
+			if err := config.SetUseADC(false); err != nil {
+				return fmt.Errorf("could not save config: %w", err)
+			}
+			if err := config.SetAccount(""); err != nil {
+				return fmt.Errorf("could not save config: %w", err)
+			}
+			if err := config.SetScopes(nil); err != nil {
+				return fmt.Errorf("could not save config: %w", err)
+			}
+			if err := config.SetNoBrowser(false); err != nil {
+				return fmt.Errorf("could not save config: %w", err)
+			}
+
+			// Cached results were fetched with this profile's credentials
+			c, err := cache.Open()
+			if err != nil {
+				return err
+			}
+			cleared, err := c.ClearProfile(config.Profile())
+			if err != nil {
+				return err
+			}
 
 			green := color.New(color.FgGreen).SprintFunc()
 			fmt.Printf("%s Logged out successfully\n", green("✓"))
+			if revoked {
+				fmt.Println("  Token revoked at Google")
+			}
+			if cleared > 0 {
+				fmt.Printf("  Removed %s cached responses\n", output.FormatCount(cleared))
+			}
+			printProfile()
 
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&localOnly, "local-only", false, "Only remove the token from this machine; don't revoke it at Google")
+
+	return cmd
 }
 
 func newAuthStatusCmd() *cobra.Command {
@@ -151,7 +399,18 @@ func newAuthStatusCmd() *cobra.Command {
 				return fmt.Errorf("could not initialize config: %w", err)
 			}
 
-			info, err := auth.GetTokenInfo()
+			switch auth.ActiveSource() {
+			case auth.SourceAccessToken:
+				printAccessTokenStatus()
+				return nil
+			case auth.SourceServiceAccount:
+				return printServiceAccountStatus(config.GetServiceAccountKey())
+			case auth.SourceADC:
+				printADCStatus(cmd.Context())
+				return nil
+			}
+
+			info, err := auth.GetTokenInfo(config.Profile())
 			if err != nil {
 				return fmt.Errorf("could not get token info: %w", err)
 			}
@@ -159,6 +418,7 @@ func newAuthStatusCmd() *cobra.Command {
 			if !info.HasToken {
 				yellow := color.New(color.FgYellow).SprintFunc()
 				fmt.Printf("%s Not logged in\n", yellow("!"))
+				printProfile()
 				fmt.Println("Run 'gsc auth login' to authenticate")
 				return nil
 			}
@@ -168,6 +428,24 @@ func newAuthStatusCmd() *cobra.Command {
 
 			fmt.Println("Authentication Status:")
 			fmt.Printf("  Logged in: %s\n", green("Yes"))
+			fmt.Printf("  Source:    %s\n", auth.SourceOAuth)
+			account := config.GetAccount()
+			if account == "" {
+				account = "unknown (run 'gsc auth login' again to record it)"
+			}
+			fmt.Printf("  Account:   %s\n", account)
+			if config.Profile() != config.DefaultProfile {
+				fmt.Printf("  Profile:   %s\n", config.Profile())
+			}
+
+			if store, err := auth.Store(); err == nil {
+				fmt.Printf("  Stored in: %s\n", store.Name())
+			}
+			var scopes []string
+			for _, scope := range auth.ProfileScopes() {
+				scopes = append(scopes, auth.ShortScope(scope))
+			}
+			fmt.Printf("  Scopes:    %s\n", strings.Join(scopes, ", "))
 
 			if info.IsExpired {
 				fmt.Printf("  Token:     %s (will refresh on next use)\n", red("Expired"))
@@ -185,3 +463,230 @@ func newAuthStatusCmd() *cobra.Command {
 		},
 	}
 }
+
+// printServiceAccountStatus shows which service account is in use
+func printServiceAccountStatus(keyPath string) error {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	sa, err := auth.LoadServiceAccount(keyPath)
+	if err != nil {
+		return err
+	}
+
+	_, source := config.Get("service_account_key")
+
+	fmt.Println("Authentication Status:")
+	fmt.Printf("  Logged in: %s\n", green("Yes"))
+	fmt.Printf("  Source:    %s\n", auth.SourceServiceAccount)
+	fmt.Printf("  Account:   %s\n", sa.Email)
+	if subject := config.GetServiceAccountSubject(); subject != "" {
+		fmt.Printf("  Acting as: %s\n", subject)
+	}
+	if config.Profile() != config.DefaultProfile {
+		fmt.Printf("  Profile:   %s\n", config.Profile())
+	}
+	fmt.Printf("  Key:       %s (from %s)\n", keyPath, source)
+
+	if siteURL := config.GetSiteURL(); siteURL != "" {
+		fmt.Printf("  Site:      %s\n", siteURL)
+	}
+
+	return nil
+}
+
+// printAccessTokenStatus shows that a bare access token is in use
+func printAccessTokenStatus() {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	from := "GSC_ACCESS_TOKEN"
+	if accessToken != "" {
+		from = "--access-token"
+	}
+
+	fmt.Println("Authentication Status:")
+	fmt.Printf("  Logged in: %s\n", green("Yes"))
+	fmt.Printf("  Source:    %s (from %s)\n", auth.SourceAccessToken, from)
+	fmt.Println("  Token:     used as given; it is not refreshed when it expires")
+
+	if siteURL := config.GetSiteURL(); siteURL != "" {
+		fmt.Printf("  Site:      %s\n", siteURL)
+	}
+}
+
+// printADCStatus shows that Application Default Credentials are in use
+func printADCStatus(ctx context.Context) {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	account := auth.ADCAccount(ctx)
+	if account == "" {
+		account = "unknown"
+	}
+
+	fmt.Println("Authentication Status:")
+	fmt.Printf("  Logged in: %s\n", green("Yes"))
+	fmt.Printf("  Source:    %s\n", auth.SourceADC)
+	fmt.Printf("  Account:   %s\n", account)
+	if config.Profile() != config.DefaultProfile {
+		fmt.Printf("  Profile:   %s\n", config.Profile())
+	}
+
+	if siteURL := config.GetSiteURL(); siteURL != "" {
+		fmt.Printf("  Site:      %s\n", siteURL)
+	}
+}
+
+// printProfile names the active profile, unless it is the default one
+func printProfile() {
+	if config.Profile() != config.DefaultProfile {
+		fmt.Printf("  Profile: %s\n", config.Profile())
+	}
+}
+
+func newAuthListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List login profiles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.Init(); err != nil {
+				return fmt.Errorf("could not initialize config: %w", err)
+			}
+
+			table := output.NewTable()
+			table.SetHeaders("PROFILE", "ACCOUNT", "SITE URL", "")
+
+			for _, p := range config.Profiles() {
+				account := p.Account
+				switch {
+				case p.ServiceAccountKey != "":
+					if account == "" {
+						account = p.ServiceAccountKey
+					}
+					account += " (service account)"
+				case !auth.HasToken(p.Name):
+					account = output.Dim("not logged in")
+				case account == "":
+					account = output.Dim("unknown")
+				}
+
+				marker := ""
+				if p.Name == config.Profile() {
+					marker = output.Green("(current)")
+				}
+				table.Append([]string{p.Name, account, p.SiteURL, marker})
+			}
+
+			table.Render()
+
+			fmt.Println()
+			fmt.Println("To use a profile:")
+			fmt.Println("  gsc <command> --profile <name>")
+
+			return nil
+		},
+	}
+}
+
+func newAuthMigrateCmd() *cobra.Command {
+	var from string
+	var to string
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Move stored tokens to another token store",
+		Long: `Move the OAuth tokens of every profile to another token store and use
+that store from now on.
+
+Token stores:
+  keyring  OS keychain (macOS Keychain, Windows Credential Manager, Secret Service)
+  file     Encrypted file, keyed by GSC_STORE_PASSPHRASE or GSC_STORE_KEY
+  env      GSC_TOKEN (GSC_TOKEN_<PROFILE> for other profiles), read-only
+
+By default tokens go in the keychain if it is reachable and in the encrypted
+file otherwise, and that choice is saved at the first login. Migrating to env prints the variables to set instead of
+moving anything.
+
+Examples:
+  GSC_STORE_PASSPHRASE=... gsc auth migrate --to file
+  gsc auth migrate --to env`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.Init(); err != nil {
+				return fmt.Errorf("could not initialize config: %w", err)
+			}
+
+			if to == "" {
+				return fmt.Errorf("--to is required (valid: %s)", strings.Join(auth.StoreNames, ", "))
+			}
+
+			if from == "" {
+				from = config.GetTokenStore()
+			}
+			src, err := auth.OpenTokenStore(from)
+			if err != nil {
+				return err
+			}
+			dst, err := auth.OpenTokenStore(to)
+			if err != nil {
+				return err
+			}
+			if src.Name() == dst.Name() {
+				return fmt.Errorf("tokens are already in the %s store", dst.Name())
+			}
+
+			green := color.New(color.FgGreen).SprintFunc()
+
+			moved := 0
+			for _, p := range config.Profiles() {
+				token, err := src.Get(p.Name)
+				if err != nil {
+					return fmt.Errorf("could not read token for profile %s: %w", p.Name, err)
+				}
+				if token == nil {
+					continue
+				}
+
+				if dst.Name() == auth.StoreEnv {
+					name, err := auth.EnvTokenVar(p.Name)
+					if err != nil {
+						return err
+					}
+					data, err := json.Marshal(token)
+					if err != nil {
+						return fmt.Errorf("could not encode token: %w", err)
+					}
+					fmt.Printf("%s='%s'\n", name, data)
+					moved++
+					continue
+				}
+
+				if err := dst.Set(p.Name, token); err != nil {
+					return fmt.Errorf("could not save token for profile %s: %w", p.Name, err)
+				}
+				if err := src.Delete(p.Name); err != nil && !errors.Is(err, auth.ErrReadOnly) {
+					return fmt.Errorf("could not remove token for profile %s: %w", p.Name, err)
+				}
+				moved++
+			}
+
+			if dst.Name() == auth.StoreEnv {
+				if moved == 0 {
+					return fmt.Errorf("no tokens found in the %s store", src.Name())
+				}
+				fmt.Fprintln(os.Stderr, "Set these variables where gsc runs. The tokens stay in the", src.Name(), "store.")
+				return nil
+			}
+
+			if err := config.SetTokenStore(dst.Name()); err != nil {
+				return fmt.Errorf("could not save token store: %w", err)
+			}
+
+			fmt.Printf("%s Moved %d token(s) from %s to %s\n", green("✓"), moved, src.Name(), dst.Name())
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&to, "to", "", "Token store to move tokens to (keyring, file, env)")
+	cmd.Flags().StringVar(&from, "from", "", "Token store to move tokens from (default: the current one)")
+
+	return cmd
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sivori/gsc-cli/internal/cache"
+	"github.com/sivori/gsc-cli/internal/output"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func newCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the API response cache",
+		Long: `Manage the on-disk cache of API responses.
+
+Repeated queries are answered from the cache. Final data for days older than
+a week never changes, so it is kept until cleared; ranges that include recent
+days expire after an hour, and fresh (--fresh) data after 15 minutes. Use
+--no-cache on any command to bypass it.`,
+	}
+
+	cmd.AddCommand(newCacheStatsCmd())
+	cmd.AddCommand(newCacheClearCmd())
+
+	return cmd
+}
+
+func newCacheStatsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Show cache size and age",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := cache.Open()
+			if err != nil {
+				return err
+			}
+
+			stats, err := c.Stats()
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Cache: %s\n", c.Dir())
+			fmt.Printf("  Entries: %s (%s expired)\n", output.FormatCount(stats.Entries), output.FormatCount(stats.Expired))
+			fmt.Printf("  Size:    %s\n", formatBytes(stats.Bytes))
+			if stats.Entries > 0 {
+				fmt.Printf("  Oldest:  %s\n", stats.Oldest.Local().Format("2006-01-02 15:04"))
+				fmt.Printf("  Newest:  %s\n", stats.Newest.Local().Format("2006-01-02 15:04"))
+			}
+
+			return nil
+		},
+	}
+}
+
+func newCacheClearCmd() *cobra.Command {
+	var expiredOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Remove cached responses",
+		Long: `Remove cached API responses.
+
+Examples:
+  gsc cache clear                   # Remove everything
+  gsc cache clear --expired         # Only remove expired entries`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := cache.Open()
+			if err != nil {
+				return err
+			}
+
+			removed, err := c.Clear(expiredOnly)
+			if err != nil {
+				return err
+			}
+
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s Removed %s cache entries\n", green("✓"), output.FormatCount(removed))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&expiredOnly, "expired", false, "Only remove expired entries")
+
+	return cmd
+}
+
+// formatBytes formats a size in bytes with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/sivori/gsc-cli/internal/api"
 	"github.com/sivori/gsc-cli/internal/output"
@@ -13,14 +14,17 @@ import (
 
 func newCompareCmd() *cobra.Command {
 	var (
-		period    string
-		fromStart string
-		fromEnd   string
-		toStart   string
-		toEnd     string
-		limit     int
-		csvFile   string
-		sortBy    string
+		period     string
+		against    string
+		fromStart  string
+		fromEnd    string
+		toStart    string
+		toEnd      string
+		limit      int
+		csvFile    string
+		sortBy     string
+		searchType string
+		dimension  string
 	)
 
 	cmd := &cobra.Command{
@@ -31,67 +35,140 @@ func newCompareCmd() *cobra.Command {
 Examples:
   gsc compare --period week         # This week vs last week
   gsc compare --period month        # This month vs last month
+  gsc compare --period week --against year-weekday  # YoY, same weekdays
+  gsc compare --period calendar-quarter  # Last full quarter vs the one before
+  gsc compare --from-start 2025-01-01 --from-end 2025-01-15  # vs preceding 15 days
+  gsc compare --from-start last-month --against year  # Last month vs same month last year
+  gsc compare --from-start mtd --against year  # Month to date vs same dates last year
   gsc compare --from-start 2025-01-01 --from-end 2025-01-15 \
               --to-start 2024-12-15 --to-end 2024-12-31
   gsc compare --csv comparison.csv
-  gsc compare --sort clicks         # Sort by clicks delta`,
+  gsc compare --sort clicks         # Sort by clicks delta
+  gsc compare --type image          # Compare image search
+  gsc compare --dimension page      # Compare pages instead of queries
+  gsc compare --dimension query,page  # Compare query×page pairs`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
 			if siteURL == "" {
 				return fmt.Errorf("no site configured - run 'gsc auth login' or use --site")
 			}
 
-			client, err := api.NewClient(siteURL)
+			var err error
+			if searchType, err = api.NormalizeSearchType(searchType); err != nil {
+				return err
+			}
+			if sortBy == "position" && !api.HasPosition(searchType) {
+				return fmt.Errorf("cannot sort by position: search type %s does not report position", searchType)
+			}
+
+			dimensions, err := parseDimensions(dimension)
+			if err != nil {
+				return err
+			}
+			for _, dim := range dimensions {
+				if dim == "date" {
+					return fmt.Errorf("cannot compare by date: the two periods never share a date")
+				}
+			}
+
+			// An explicit previous period leaves nothing for --against to pick
+			if (toStart != "" || toEnd != "") && cmd.Flags().Changed("against") {
+				return fmt.Errorf("--against cannot be combined with --to-start or --to-end")
+			}
+
+			client, closeClient, err := newQuerier(ctx)
+			if err != nil {
+				return err
+			}
+			defer closeClient()
+
+			// Anchor default ranges on the latest day with data
+			needed := api.NeedsDataDate(fromStart, fromEnd) || (toStart != "" || toEnd != "") && api.NeedsDataDate(toStart, toEnd)
+			dates, freshness, err := anchorDataDate(ctx, client, searchType, needed)
 			if err != nil {
 				return err
 			}
 
 			// Determine date ranges
-			var currentStart, currentEnd, prevStart, prevEnd string
-			if fromStart != "" && fromEnd != "" && toStart != "" && toEnd != "" {
-				currentStart, currentEnd = fromStart, fromEnd
-				prevStart, prevEnd = toStart, toEnd
-			} else {
-				p := api.GetComparisonPeriod(period)
-				currentStart, currentEnd = p.CurrentStart, p.CurrentEnd
-				prevStart, prevEnd = p.PreviousStart, p.PreviousEnd
-			}
-
-			// Query current period
-			currentResult, err := client.Query(api.QueryRequest{
+			var p api.ComparisonPeriod
+			switch {
+			case toStart != "" || toEnd != "":
+				if fromStart == "" {
+					return fmt.Errorf("--to-start and --to-end require --from-start")
+				}
+				current, err := dates.ParseRange(fromStart, fromEnd)
+				if err != nil {
+					return fmt.Errorf("current period: %w", err)
+				}
+				previous, err := dates.ParseRange(toStart, toEnd)
+				if err != nil {
+					return fmt.Errorf("previous period: %w", err)
+				}
+				p = api.ComparisonPeriod{
+					CurrentStart:  current.Start,
+					CurrentEnd:    current.End,
+					PreviousStart: previous.Start,
+					PreviousEnd:   previous.End,
+				}
+			case fromStart != "" || fromEnd != "":
+				current, err := dates.ParseRange(fromStart, fromEnd)
+				if err != nil {
+					return fmt.Errorf("current period: %w", err)
+				}
+				if p, err = api.ComparePeriod(current.Start, current.End, against); err != nil {
+					return err
+				}
+			default:
+				if p, err = dates.ComparisonPeriod(period, against); err != nil {
+					return err
+				}
+			}
+			currentStart, currentEnd := p.CurrentStart, p.CurrentEnd
+			prevStart, prevEnd := p.PreviousStart, p.PreviousEnd
+
+			// Fetch every row for both periods so the join is not skewed
+			// by rows that fall outside a truncated top-N in one period
+			progress := output.NewProgress("Fetching current period:")
+			currentResult, err := client.QueryAllWithProgress(ctx, api.QueryRequest{
 				StartDate:  currentStart,
 				EndDate:    currentEnd,
-				Dimensions: []string{"query"},
-				RowLimit:   int64(limit * 2), // Fetch more to account for new queries
-			})
+				Dimensions: dimensions,
+				SearchType: searchType,
+				DataState:  dataState(),
+			}, progress.Update)
+			progress.Done()
 			if err != nil {
 				return fmt.Errorf("could not query current period: %w", err)
 			}
 
-			// Query previous period
-			prevResult, err := client.Query(api.QueryRequest{
+			progress = output.NewProgress("Fetching previous period:")
+			prevResult, err := client.QueryAllWithProgress(ctx, api.QueryRequest{
 				StartDate:  prevStart,
 				EndDate:    prevEnd,
-				Dimensions: []string{"query"},
-				RowLimit:   int64(limit * 2),
-			})
+				Dimensions: dimensions,
+				SearchType: searchType,
+				DataState:  dataState(),
+			}, progress.Update)
+			progress.Done()
 			if err != nil {
 				return fmt.Errorf("could not query previous period: %w", err)
 			}
 
 			// Build comparison
-			rows := buildComparison(currentResult.Rows, prevResult.Rows)
+			rows := buildComparison(currentResult.Rows, prevResult.Rows, dimensions)
 
 			// Sort
 			sortComparison(rows, sortBy)
 
-			// Limit results
-			if len(rows) > limit {
+			// Limit results only after sorting the full join
+			if limit > 0 && len(rows) > limit {
 				rows = rows[:limit]
 			}
 
 			// Output
 			if csvFile != "" {
-				if err := output.WriteComparisonCSV(csvFile, rows); err != nil {
+				if err := output.WriteComparisonCSV(csvFile, searchType, dimensions, rows); err != nil {
 					return err
 				}
 				green := color.New(color.FgGreen).SprintFunc()
@@ -101,6 +178,8 @@ Examples:
 
 			if jsonOutput {
 				return output.PrintComparisonJSON(
+					searchType,
+					dimensions,
 					output.Period{Start: currentStart, End: currentEnd},
 					output.Period{Start: prevStart, End: prevEnd},
 					rows,
@@ -109,8 +188,10 @@ Examples:
 
 			// Print header
 			fmt.Printf("Comparison for %s\n", output.Cyan(siteURL))
+			fmt.Printf("Search type: %s\n", searchType)
 			fmt.Printf("Current:  %s to %s\n", currentStart, currentEnd)
 			fmt.Printf("Previous: %s to %s\n", prevStart, prevEnd)
+			printFreshness(freshness, currentEnd)
 			fmt.Println()
 
 			if len(rows) == 0 {
@@ -120,18 +201,34 @@ Examples:
 
 			// Print table
 			table := output.NewTable()
-			table.SetHeaders("QUERY", "CLICKS", "Δ", "IMPR", "Δ", "POS", "Δ")
+			headers := make([]string, 0, len(dimensions)+6)
+			for _, dim := range dimensions {
+				headers = append(headers, strings.ToUpper(dim))
+			}
+			headers = append(headers, "CLICKS", "Δ", "IMPR", "Δ", "POS", "Δ")
+			table.SetHeaders(headers...)
 
 			for _, row := range rows {
-				table.Append([]string{
-					output.TruncateString(row.Query, 40),
+				record := make([]string, 0, len(headers))
+				for i, dim := range dimensions {
+					label := row.Keys[i]
+					switch dim {
+					case "page":
+						label = output.TruncateString(label, 50)
+					case "query":
+						label = output.TruncateString(label, 40)
+					}
+					record = append(record, label)
+				}
+
+				table.Append(append(record,
 					output.FormatNumber(row.CurrentClicks),
 					output.FormatDelta(row.ClicksDelta, true),
 					output.FormatNumber(row.CurrentImpressions),
 					output.FormatDelta(row.ImpressionsDelta, true),
 					output.FormatPosition(row.CurrentPosition),
 					output.FormatDelta(row.PositionDelta, false),
-				})
+				))
 			}
 
 			table.Render()
@@ -139,47 +236,59 @@ Examples:
 		},
 	}
 
-	cmd.Flags().StringVar(&period, "period", "week", "Comparison period (week, month)")
-	cmd.Flags().StringVar(&fromStart, "from-start", "", "Current period start (YYYY-MM-DD)")
-	cmd.Flags().StringVar(&fromEnd, "from-end", "", "Current period end (YYYY-MM-DD)")
-	cmd.Flags().StringVar(&toStart, "to-start", "", "Previous period start (YYYY-MM-DD)")
-	cmd.Flags().StringVar(&toEnd, "to-end", "", "Previous period end (YYYY-MM-DD)")
-	cmd.Flags().IntVar(&limit, "limit", 50, "Maximum number of results")
+	cmd.Flags().StringVar(&period, "period", "week", "Comparison period (week, month, quarter, calendar-month, calendar-quarter)")
+	cmd.Flags().StringVar(&against, "against", "previous", "Compare against (previous, year, year-weekday)")
+	cmd.Flags().StringVar(&fromStart, "from-start", "", "Current period start date or expression")
+	cmd.Flags().StringVar(&fromEnd, "from-end", "", "Current period end date or expression")
+	cmd.Flags().StringVar(&toStart, "to-start", "", "Previous period start date or expression")
+	cmd.Flags().StringVar(&toEnd, "to-end", "", "Previous period end date or expression")
+	cmd.Flags().IntVar(&limit, "limit", 50, "Maximum number of results (0 for all)")
 	cmd.Flags().StringVar(&csvFile, "csv", "", "Export to CSV file")
 	cmd.Flags().StringVar(&sortBy, "sort", "clicks", "Sort by (clicks, impressions, position)")
+	cmd.Flags().StringVar(&dimension, "dimension", "query", "Dimensions to compare by, comma-separated (query, page, country, device)")
+	cmd.Flags().StringVar(&searchType, "type", "web", "Search type (web, image, video, news, discover, googleNews)")
 
 	return cmd
 }
 
-func buildComparison(current, previous []api.QueryRow) []output.ComparisonRow {
+// comparisonKey joins a row's dimension values into a composite map key
+func comparisonKey(row api.QueryRow, dimensions []string) string {
+	keys := make([]string, len(dimensions))
+	for i, dim := range dimensions {
+		keys[i] = row.Dimension(dim)
+	}
+	return strings.Join(keys, "\x00")
+}
+
+func buildComparison(current, previous []api.QueryRow, dimensions []string) []output.ComparisonRow {
 	// Build lookup maps
 	currentMap := make(map[string]api.QueryRow)
 	for _, row := range current {
-		currentMap[row.Query] = row
+		currentMap[comparisonKey(row, dimensions)] = row
 	}
 
 	prevMap := make(map[string]api.QueryRow)
 	for _, row := range previous {
-		prevMap[row.Query] = row
+		prevMap[comparisonKey(row, dimensions)] = row
 	}
 
-	// Collect all unique queries
-	queries := make(map[string]bool)
-	for q := range currentMap {
-		queries[q] = true
+	// Collect all unique keys
+	keys := make(map[string]bool)
+	for k := range currentMap {
+		keys[k] = true
 	}
-	for q := range prevMap {
-		queries[q] = true
+	for k := range prevMap {
+		keys[k] = true
 	}
 
 	// Build comparison rows
 	var rows []output.ComparisonRow
-	for query := range queries {
-		curr := currentMap[query]
-		prev := prevMap[query]
+	for key := range keys {
+		curr := currentMap[key]
+		prev := prevMap[key]
 
 		row := output.ComparisonRow{
-			Query:               query,
+			Keys:                strings.Split(key, "\x00"),
 			CurrentClicks:       curr.Clicks,
 			PreviousClicks:      prev.Clicks,
 			ClicksDelta:         curr.Clicks - prev.Clicks,
@@ -212,8 +321,13 @@ func sortComparison(rows []output.ComparisonRow, sortBy string) {
 			return rows[i].CurrentImpressions > rows[j].CurrentImpressions
 		})
 	case "position":
+		// Rows missing from the current period have no position; keep them last
 		sort.Slice(rows, func(i, j int) bool {
-			return rows[i].CurrentPosition < rows[j].CurrentPosition
+			a, b := rows[i].CurrentPosition, rows[j].CurrentPosition
+			if a == 0 || b == 0 {
+				return b == 0 && a != 0
+			}
+			return a < b
 		})
 	default: // clicks
 		sort.Slice(rows, func(i, j int) bool {
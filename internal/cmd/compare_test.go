@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/sivori/gsc-cli/internal/output"
+)
+
+func TestSortComparisonByPositionPutsMissingLast(t *testing.T) {
+	rows := []output.ComparisonRow{
+		{Keys: []string{"gone"}, CurrentPosition: 0},
+		{Keys: []string{"third"}, CurrentPosition: 8.5},
+		{Keys: []string{"first"}, CurrentPosition: 1.2},
+		{Keys: []string{"also-gone"}, CurrentPosition: 0},
+		{Keys: []string{"second"}, CurrentPosition: 3},
+	}
+
+	sortComparison(rows, "position")
+
+	want := []string{"first", "second", "third"}
+	for i, key := range want {
+		if rows[i].Keys[0] != key {
+			t.Fatalf("rows[%d] = %s, want %s", i, rows[i].Keys[0], key)
+		}
+	}
+	for _, row := range rows[3:] {
+		if row.CurrentPosition != 0 {
+			t.Errorf("row %s with a position sorted after rows without one", row.Keys[0])
+		}
+	}
+}
@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/sivori/gsc-cli/internal/config"
+	"github.com/sivori/gsc-cli/internal/output"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -46,6 +49,11 @@ Examples:
 			green := color.New(color.FgGreen).SprintFunc()
 			fmt.Printf("%s Default site set to: %s\n", green("✓"), siteURL)
 
+			userFile, _ := config.Files()
+			if _, source := config.Get("site_url"); source != userFile {
+				fmt.Printf("%s %s overrides it here\n", output.Yellow("!"), source)
+			}
+
 			return nil
 		},
 	}
@@ -55,14 +63,57 @@ func newConfigShowCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "show",
 		Short: "Show current configuration",
+		Long: `Show the effective value of every setting and where it came from.
+
+Settings are read, in order of precedence, from:
+  1. Environment variables, e.g. GSC_SITE or GSC_SOURCE
+  2. The closest .gsc.yaml in the current directory or its parents
+  3. The user config file ($XDG_CONFIG_HOME/gsc-cli/config.yaml, or
+     ~/.config/gsc-cli/config.yaml)
+
+Command-line flags override all of them.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := config.Init(); err != nil {
 				return fmt.Errorf("could not initialize config: %w", err)
 			}
 
-			fmt.Println("Current configuration:")
-			fmt.Printf("  Site URL:           %s\n", config.GetSiteURL())
-			fmt.Printf("  Client secret path: %s\n", config.GetClientSecretPath())
+			userFile, projectFile := config.Files()
+			if _, err := os.Stat(userFile); os.IsNotExist(err) {
+				userFile += output.Dim(" (not created yet)")
+			}
+			if projectFile == "" {
+				projectFile = output.Dim("none")
+			}
+
+			fmt.Printf("Config file:  %s\n", userFile)
+			fmt.Printf("Project file: %s\n", projectFile)
+			fmt.Printf("Profile:      %s\n", config.Profile())
+			if account := config.GetAccount(); account != "" {
+				fmt.Printf("Account:      %s\n", account)
+			}
+			fmt.Println()
+
+			table := output.NewTable()
+			table.SetHeaders("SETTING", "VALUE", "SOURCE")
+
+			for _, v := range config.Values() {
+				value := v.Value
+				if value == "" {
+					value = output.Dim("-")
+				}
+				source := v.Source
+				if source == config.SourceDefault {
+					source = output.Dim(source)
+				}
+				table.Append([]string{v.Name, value, source})
+			}
+
+			table.Render()
+
+			if ignored := config.IgnoredProjectSettings(); len(ignored) > 0 {
+				fmt.Printf("\n%s Ignored in %s (credentials are only read from the environment or the user config): %s\n",
+					output.Yellow("!"), config.ProjectFile, strings.Join(ignored, ", "))
+			}
 
 			return nil
 		},
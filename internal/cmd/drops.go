@@ -13,11 +13,16 @@ import (
 
 func newDropsCmd() *cobra.Command {
 	var (
-		threshold float64
-		minClicks float64
-		days      int
-		limit     int
-		csvFile   string
+		threshold  float64
+		minClicks  float64
+		days       int
+		startDate  string
+		endDate    string
+		period     string
+		against    string
+		limit      int
+		csvFile    string
+		searchType string
 	)
 
 	cmd := &cobra.Command{
@@ -30,48 +35,84 @@ Examples:
   gsc drops --threshold 3           # Drops > 3 positions
   gsc drops --min-clicks 10         # Only queries with 10+ clicks
   gsc drops --days 14               # Compare 14-day periods
-  gsc drops --csv drops.csv`,
+  gsc drops --period month --against year-weekday  # Last 30 days vs same weekdays last year
+  gsc drops --start last-month      # Last calendar month vs the 30/31 days before
+  gsc drops --csv drops.csv
+  gsc drops --type video            # Drops in video search`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
 			if siteURL == "" {
 				return fmt.Errorf("no site configured - run 'gsc auth login' or use --site")
 			}
 
-			client, err := api.NewClient(siteURL)
+			var err error
+			if searchType, err = api.NormalizeSearchType(searchType); err != nil {
+				return err
+			}
+			if !api.HasPosition(searchType) {
+				return fmt.Errorf("search type %s does not report position - ranking drops are unavailable", searchType)
+			}
+
+			client, closeClient, err := newQuerier(ctx)
+			if err != nil {
+				return err
+			}
+			defer closeClient()
+
+			// Anchor default ranges on the latest day with data
+			dates, freshness, err := anchorDataDate(ctx, client, searchType, api.NeedsDataDate(startDate, endDate))
 			if err != nil {
 				return err
 			}
 
 			// Calculate date ranges
-			p := api.GetComparisonPeriod("week")
-			if days > 7 {
-				// Custom period based on days
-				currentStart, currentEnd := api.DateRangeForDays(days)
-				prevStart, prevEnd := api.DateRangeForDays(days * 2)
-				p = api.ComparisonPeriod{
-					CurrentStart:  currentStart,
-					CurrentEnd:    currentEnd,
-					PreviousStart: prevStart,
-					PreviousEnd:   prevEnd,
+			var p api.ComparisonPeriod
+			if period == "" && !cmd.Flags().Changed("days") {
+				period = api.PeriodWeek
+			}
+			switch {
+			case startDate != "" || endDate != "":
+				current, err := dates.ParseRange(startDate, endDate)
+				if err != nil {
+					return err
+				}
+				p, err = api.ComparePeriod(current.Start, current.End, against)
+				if err != nil {
+					return err
 				}
+			case period != "":
+				p, err = dates.ComparisonPeriod(period, against)
+			default:
+				// Custom period based on days
+				currentStart, currentEnd := dates.RangeForDays(days)
+				p, err = api.ComparePeriod(currentStart, currentEnd, against)
+			}
+			if err != nil {
+				return err
 			}
 
 			// Query current period
-			currentResult, err := client.Query(api.QueryRequest{
+			currentResult, err := client.Query(ctx, api.QueryRequest{
 				StartDate:  p.CurrentStart,
 				EndDate:    p.CurrentEnd,
 				Dimensions: []string{"query"},
 				RowLimit:   25000,
+				SearchType: searchType,
+				DataState:  dataState(),
 			})
 			if err != nil {
 				return fmt.Errorf("could not query current period: %w", err)
 			}
 
 			// Query previous period
-			prevResult, err := client.Query(api.QueryRequest{
+			prevResult, err := client.Query(ctx, api.QueryRequest{
 				StartDate:  p.PreviousStart,
 				EndDate:    p.PreviousEnd,
 				Dimensions: []string{"query"},
 				RowLimit:   25000,
+				SearchType: searchType,
+				DataState:  dataState(),
 			})
 			if err != nil {
 				return fmt.Errorf("could not query previous period: %w", err)
@@ -92,7 +133,7 @@ Examples:
 
 			// Output
 			if csvFile != "" {
-				if err := output.WriteDropsCSV(csvFile, drops); err != nil {
+				if err := output.WriteDropsCSV(csvFile, searchType, drops); err != nil {
 					return err
 				}
 				green := color.New(color.FgGreen).SprintFunc()
@@ -101,13 +142,21 @@ Examples:
 			}
 
 			if jsonOutput {
-				return output.PrintDropsJSON(threshold, drops)
+				return output.PrintDropsJSON(
+					searchType,
+					output.Period{Start: p.CurrentStart, End: p.CurrentEnd},
+					output.Period{Start: p.PreviousStart, End: p.PreviousEnd},
+					threshold,
+					drops,
+				)
 			}
 
 			// Print header
 			fmt.Printf("Ranking drops for %s\n", output.Cyan(siteURL))
+			fmt.Printf("Search type: %s\n", searchType)
 			fmt.Printf("Current:  %s to %s\n", p.CurrentStart, p.CurrentEnd)
 			fmt.Printf("Previous: %s to %s\n", p.PreviousStart, p.PreviousEnd)
+			printFreshness(freshness, p.CurrentEnd)
 			fmt.Printf("Threshold: >%.1f positions\n", threshold)
 			if minClicks > 0 {
 				fmt.Printf("Min clicks: %.0f\n", minClicks)
@@ -146,8 +195,13 @@ Examples:
 	cmd.Flags().Float64Var(&threshold, "threshold", 5, "Minimum position drop to flag")
 	cmd.Flags().Float64Var(&minClicks, "min-clicks", 0, "Minimum clicks in previous period")
 	cmd.Flags().IntVar(&days, "days", 7, "Number of days per period")
+	cmd.Flags().StringVar(&startDate, "start", "", "Current period start date or expression (YYYY-MM-DD, last-month, ...)")
+	cmd.Flags().StringVar(&endDate, "end", "", "Current period end date or expression")
+	cmd.Flags().StringVar(&period, "period", "", "Comparison period (week, month, quarter, calendar-month, calendar-quarter)")
+	cmd.Flags().StringVar(&against, "against", "previous", "Compare against (previous, year, year-weekday)")
 	cmd.Flags().IntVar(&limit, "limit", 50, "Maximum number of results")
 	cmd.Flags().StringVar(&csvFile, "csv", "", "Export to CSV file")
+	cmd.Flags().StringVar(&searchType, "type", "web", "Search type (web, image, video, news, discover, googleNews)")
 
 	return cmd
 }
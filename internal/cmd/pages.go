@@ -8,20 +8,23 @@ import (
 	"github.com/sivori/gsc-cli/internal/api"
 	"github.com/sivori/gsc-cli/internal/output"
 
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
 func newPagesCmd() *cobra.Command {
 	var (
-		days      int
-		startDate string
-		endDate   string
-		limit     int
-		filter    string
-		csvFile   string
-		query     string
-		fullURL   bool
+		days       int
+		startDate  string
+		endDate    string
+		limit      int
+		filters    []string
+		csvFile    string
+		ndjsonFile string
+		query      string
+		fullURL    bool
+		searchType string
+		shard      string
+		workers    int
 	)
 
 	cmd := &cobra.Command{
@@ -38,76 +41,87 @@ Examples:
   gsc pages --query "keyword"     # Pages ranking for a specific query
   gsc pages --filter "page:*/blog/*"  # Filter to blog pages only
   gsc pages --full                # Show full URLs (not truncated)
+  gsc pages --type discover       # Discover traffic by page
   gsc pages --csv output.csv      # Export to CSV
-  gsc pages --json                # JSON output`,
+  gsc pages --json                # JSON output
+  gsc pages --limit 0 --ndjson pages.ndjson  # Stream every page to disk
+  gsc pages --limit 0 --shard day --csv pages.csv  # Every page, one request per day`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
 			if siteURL == "" {
 				return fmt.Errorf("no site configured - run 'gsc auth login' or use --site")
 			}
 
-			client, err := api.NewClient(siteURL)
+			var err error
+			if searchType, err = api.NormalizeSearchType(searchType); err != nil {
+				return err
+			}
+
+			client, closeClient, err := newQuerier(ctx)
+			if err != nil {
+				return err
+			}
+			defer closeClient()
+
+			// Anchor default ranges on the latest day with data
+			dates, freshness, err := anchorDataDate(ctx, client, searchType, api.NeedsDataDate(startDate, endDate))
 			if err != nil {
 				return err
 			}
 
 			// Determine date range
-			var start, end string
-			if startDate != "" && endDate != "" {
-				start, end = startDate, endDate
-			} else if days > 0 {
-				start, end = api.DateRangeForDays(days)
-			} else {
-				start, end = api.DefaultDateRange()
+			dateRange, err := resolveDateRange(dates, startDate, endDate, days)
+			if err != nil {
+				return err
 			}
+			start, end := dateRange.Start, dateRange.End
 
 			// Build filters
-			var filters []api.Filter
-			if filter != "" {
-				f, err := parseFilter(filter)
-				if err != nil {
-					return err
-				}
-				filters = append(filters, f)
+			apiFilters, err := parseFilters(filters)
+			if err != nil {
+				return err
 			}
 
 			// Add query filter if specified
 			if query != "" {
-				filters = append(filters, api.Filter{
+				apiFilters = append(apiFilters, api.Filter{
 					Dimension:  "query",
-					Operator:   "contains",
+					Operator:   api.OperatorContains,
 					Expression: query,
 				})
 			}
 
-			// Execute query with page dimension
-			result, err := client.Query(api.QueryRequest{
+			req := api.QueryRequest{
 				StartDate:  start,
 				EndDate:    end,
 				Dimensions: []string{"page"},
 				RowLimit:   int64(limit),
-				Filters:    filters,
-			})
-			if err != nil {
-				return err
+				Filters:    apiFilters,
+				SearchType: searchType,
+				DataState:  dataState(),
 			}
 
-			// Output
-			if csvFile != "" {
-				if err := output.WriteQueryResultCSV(csvFile, result, []string{"page"}); err != nil {
-					return err
-				}
-				green := color.New(color.FgGreen).SprintFunc()
-				fmt.Printf("%s Exported %d rows to %s\n", green("✓"), len(result.Rows), csvFile)
-				return nil
+			// Exports stream to disk as rows arrive
+			if csvFile != "" || ndjsonFile != "" {
+				return exportQuery(ctx, client, shard, workers, req, csvFile, ndjsonFile)
+			}
+
+			// Execute query with page dimension
+			result, err := runQuery(ctx, client, shard, workers, req)
+			if err != nil {
+				return err
 			}
 
 			if jsonOutput {
-				return output.PrintQueryResultJSON(result)
+				return output.PrintQueryResultJSON(result, []string{"page"})
 			}
 
 			// Print header
 			fmt.Printf("Top pages for %s\n", output.Cyan(siteURL))
+			fmt.Printf("Search type: %s\n", searchType)
 			fmt.Printf("Date range: %s to %s\n", start, end)
+			printFreshness(freshness, end)
 			if query != "" {
 				fmt.Printf("Filtered by query: %s\n", output.Cyan(query))
 			}
@@ -143,13 +157,17 @@ Examples:
 	}
 
 	cmd.Flags().IntVar(&days, "days", 0, "Number of days to query (default: 28)")
-	cmd.Flags().StringVar(&startDate, "start", "", "Start date (YYYY-MM-DD)")
-	cmd.Flags().StringVar(&endDate, "end", "", "End date (YYYY-MM-DD)")
-	cmd.Flags().IntVar(&limit, "limit", 100, "Maximum number of results")
-	cmd.Flags().StringVar(&filter, "filter", "", "Filter (e.g., page:*/blog/*)")
+	cmd.Flags().StringVar(&startDate, "start", "", "Start date or expression (YYYY-MM-DD, today-7d, last-month, mtd, ytd, ...)")
+	cmd.Flags().StringVar(&endDate, "end", "", "End date or expression (YYYY-MM-DD, yesterday, today-3d, ...)")
+	cmd.Flags().IntVar(&limit, "limit", 100, "Maximum number of results (0 for all)")
+	cmd.Flags().StringArrayVar(&filters, "filter", nil, "Filter expression, repeatable (e.g., page:*/blog/*)")
 	cmd.Flags().StringVar(&query, "query", "", "Query to filter pages by (shows pages ranking for this query)")
 	cmd.Flags().StringVar(&csvFile, "csv", "", "Export to CSV file")
+	cmd.Flags().StringVar(&ndjsonFile, "ndjson", "", "Export to newline-delimited JSON file ('-' for stdout)")
 	cmd.Flags().BoolVar(&fullURL, "full", false, "Show full URLs instead of paths")
+	cmd.Flags().StringVar(&searchType, "type", "web", "Search type (web, image, video, news, discover, googleNews)")
+	cmd.Flags().StringVar(&shard, "shard", "", "Split the query into one request per day, device or country and merge the rows")
+	cmd.Flags().IntVar(&workers, "concurrency", api.DefaultConcurrency, "Shards to fetch at once with --shard")
 
 	return cmd
 }
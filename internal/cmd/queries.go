@@ -1,11 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"iter"
+	"os"
 	"strings"
 
 	"github.com/sivori/gsc-cli/internal/api"
+	"github.com/sivori/gsc-cli/internal/auth"
+	"github.com/sivori/gsc-cli/internal/cache"
+	"github.com/sivori/gsc-cli/internal/config"
 	"github.com/sivori/gsc-cli/internal/output"
+	"github.com/sivori/gsc-cli/internal/storage"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -13,13 +20,17 @@ import (
 
 func newQueriesCmd() *cobra.Command {
 	var (
-		days      int
-		startDate string
-		endDate   string
-		limit     int
-		filter    string
-		csvFile   string
-		dimension string
+		days       int
+		startDate  string
+		endDate    string
+		limit      int
+		filters    []string
+		csvFile    string
+		ndjsonFile string
+		dimension  string
+		searchType string
+		shard      string
+		workers    int
 	)
 
 	cmd := &cobra.Command{
@@ -31,76 +42,95 @@ Examples:
   gsc queries                       # Last 28 days, top 100
   gsc queries --days 7              # Last 7 days
   gsc queries --start 2025-01-01 --end 2025-01-15
+  gsc queries --start last-month    # Previous calendar month
+  gsc queries --start ytd           # Year to date
   gsc queries --limit 500           # Top 500 queries
   gsc queries --filter "page:*/blog/*"
+  gsc queries --filter 'query:contains:shoes AND NOT query:regex:"^acme( shoes)?$"'
+  gsc queries --filter "page:*/blog/* OR page:*/news/*" --filter "device:MOBILE"
   gsc queries --csv output.csv      # Export to CSV
   gsc queries --json                # JSON output
-  gsc queries --dimension page      # Group by page instead of query`,
+  gsc queries --limit 0 --ndjson - | jq .query  # Stream every row as NDJSON
+  gsc queries --dimension page      # Group by page instead of query
+  gsc queries --dimension query,page,device  # Cross-tab by several dimensions
+  gsc queries --type discover --dimension page
+  gsc queries --start last-month --limit 0 --shard day --csv all.csv  # Every row, one request per day`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
 			if siteURL == "" {
 				return fmt.Errorf("no site configured - run 'gsc auth login' or use --site")
 			}
 
-			client, err := api.NewClient(siteURL)
+			var err error
+			if searchType, err = api.NormalizeSearchType(searchType); err != nil {
+				return err
+			}
+
+			client, closeClient, err := newQuerier(ctx)
+			if err != nil {
+				return err
+			}
+			defer closeClient()
+
+			// Anchor default ranges on the latest day with data
+			dates, freshness, err := anchorDataDate(ctx, client, searchType, api.NeedsDataDate(startDate, endDate))
 			if err != nil {
 				return err
 			}
 
 			// Determine date range
-			var start, end string
-			if startDate != "" && endDate != "" {
-				start, end = startDate, endDate
-			} else if days > 0 {
-				start, end = api.DateRangeForDays(days)
-			} else {
-				start, end = api.DefaultDateRange()
+			dateRange, err := resolveDateRange(dates, startDate, endDate, days)
+			if err != nil {
+				return err
 			}
+			start, end := dateRange.Start, dateRange.End
 
 			// Build dimensions
 			dimensions := []string{"query"}
 			if dimension != "" {
-				dimensions = []string{dimension}
-			}
-
-			// Build filters
-			var filters []api.Filter
-			if filter != "" {
-				f, err := parseFilter(filter)
+				dimensions, err = parseDimensions(dimension)
 				if err != nil {
 					return err
 				}
-				filters = append(filters, f)
 			}
 
-			// Execute query
-			result, err := client.Query(api.QueryRequest{
+			// Build filters
+			apiFilters, err := parseFilters(filters)
+			if err != nil {
+				return err
+			}
+
+			req := api.QueryRequest{
 				StartDate:  start,
 				EndDate:    end,
 				Dimensions: dimensions,
 				RowLimit:   int64(limit),
-				Filters:    filters,
-			})
-			if err != nil {
-				return err
+				Filters:    apiFilters,
+				SearchType: searchType,
+				DataState:  dataState(),
 			}
 
-			// Output
-			if csvFile != "" {
-				if err := output.WriteQueryResultCSV(csvFile, result, dimensions); err != nil {
-					return err
-				}
-				green := color.New(color.FgGreen).SprintFunc()
-				fmt.Printf("%s Exported %d rows to %s\n", green("✓"), len(result.Rows), csvFile)
-				return nil
+			// Exports stream to disk as rows arrive
+			if csvFile != "" || ndjsonFile != "" {
+				return exportQuery(ctx, client, shard, workers, req, csvFile, ndjsonFile)
+			}
+
+			// Execute query
+			result, err := runQuery(ctx, client, shard, workers, req)
+			if err != nil {
+				return err
 			}
 
 			if jsonOutput {
-				return output.PrintQueryResultJSON(result)
+				return output.PrintQueryResultJSON(result, dimensions)
 			}
 
 			// Print header
 			fmt.Printf("Search queries for %s\n", output.Cyan(siteURL))
+			fmt.Printf("Search type: %s\n", searchType)
 			fmt.Printf("Date range: %s to %s\n", start, end)
+			printFreshness(freshness, end)
 			fmt.Printf("Total results: %d\n\n", result.TotalRows)
 
 			if len(result.Rows) == 0 {
@@ -111,34 +141,34 @@ Examples:
 			// Print table
 			table := output.NewTable()
 
-			// Set headers based on dimension
-			switch dimensions[0] {
-			case "page":
-				table.SetHeaders("PAGE", "CLICKS", "IMPR", "CTR", "POS")
-			default:
-				table.SetHeaders("QUERY", "CLICKS", "IMPR", "CTR", "POS")
+			// One column per dimension, followed by the metrics
+			headers := make([]string, 0, len(dimensions)+4)
+			for _, dim := range dimensions {
+				headers = append(headers, strings.ToUpper(dim))
 			}
+			headers = append(headers, "CLICKS", "IMPR", "CTR", "POS")
+			table.SetHeaders(headers...)
 
 			for _, row := range result.Rows {
-				var label string
-				switch dimensions[0] {
-				case "page":
-					label = output.TruncateString(row.Page, 60)
-				case "country":
-					label = row.Country
-				case "device":
-					label = row.Device
-				default:
-					label = output.TruncateString(row.Query, 50)
+				record := make([]string, 0, len(headers))
+				for _, dim := range dimensions {
+					label := row.Dimension(dim)
+					switch dim {
+					case "page":
+						label = output.TruncateString(label, 60)
+					case "query":
+						label = output.TruncateString(label, 50)
+					}
+					record = append(record, label)
 				}
 
-				table.Append([]string{
-					label,
+				record = append(record,
 					output.FormatNumber(row.Clicks),
 					output.FormatNumber(row.Impressions),
 					output.FormatCTR(row.CTR),
 					output.FormatPosition(row.Position),
-				})
+				)
+				table.Append(record)
 			}
 
 			table.Render()
@@ -147,45 +177,232 @@ Examples:
 	}
 
 	cmd.Flags().IntVar(&days, "days", 0, "Number of days to query (default: 28)")
-	cmd.Flags().StringVar(&startDate, "start", "", "Start date (YYYY-MM-DD)")
-	cmd.Flags().StringVar(&endDate, "end", "", "End date (YYYY-MM-DD)")
-	cmd.Flags().IntVar(&limit, "limit", 100, "Maximum number of results")
-	cmd.Flags().StringVar(&filter, "filter", "", "Filter (e.g., page:*/blog/*, query:keyword)")
+	cmd.Flags().StringVar(&startDate, "start", "", "Start date or expression (YYYY-MM-DD, today-7d, last-month, mtd, ytd, ...)")
+	cmd.Flags().StringVar(&endDate, "end", "", "End date or expression (YYYY-MM-DD, yesterday, today-3d, ...)")
+	cmd.Flags().IntVar(&limit, "limit", 100, "Maximum number of results (0 for all)")
+	cmd.Flags().StringArrayVar(&filters, "filter", nil, "Filter expression, repeatable (e.g., page:*/blog/*, query:contains:shoes AND NOT query:brand)")
 	cmd.Flags().StringVar(&csvFile, "csv", "", "Export to CSV file")
-	cmd.Flags().StringVar(&dimension, "dimension", "", "Dimension to group by (query, page, country, device)")
+	cmd.Flags().StringVar(&ndjsonFile, "ndjson", "", "Export to newline-delimited JSON file ('-' for stdout)")
+	cmd.Flags().StringVar(&dimension, "dimension", "", "Dimensions to group by, comma-separated (query, page, country, device, date)")
+	cmd.Flags().StringVar(&searchType, "type", "web", "Search type (web, image, video, news, discover, googleNews)")
+	cmd.Flags().StringVar(&shard, "shard", "", "Split the query into one request per day, device or country and merge the rows")
+	cmd.Flags().IntVar(&workers, "concurrency", api.DefaultConcurrency, "Shards to fetch at once with --shard")
 
 	return cmd
 }
 
-// parseFilter parses a filter string like "page:*/blog/*" or "query:keyword"
-func parseFilter(s string) (api.Filter, error) {
-	parts := strings.SplitN(s, ":", 2)
-	if len(parts) != 2 {
-		return api.Filter{}, fmt.Errorf("invalid filter format: %s (expected dimension:expression)", s)
+// runQuery runs req as a single request, or split into shards with --shard.
+// A zero RowLimit fetches every row.
+func runQuery(ctx context.Context, client api.Querier, shard string, concurrency int, req api.QueryRequest) (*api.QueryResult, error) {
+	if shard == "" && req.RowLimit > 0 {
+		return client.Query(ctx, req)
+	}
+
+	progress := output.NewProgress("Fetching:")
+	defer progress.Done()
+	if shard == "" {
+		return client.QueryAllWithProgress(ctx, req, progress.Update)
+	}
+	return api.QuerySharded(ctx, client, req, shard, concurrency, progress.Update)
+}
+
+// exportQuery writes the rows of req to --csv or --ndjson. Without --shard
+// rows are streamed page by page, so memory use does not grow with the
+// export; sharded rows have to be merged in memory first.
+func exportQuery(ctx context.Context, client api.Querier, shard string, concurrency int, req api.QueryRequest, csvFile, ndjsonFile string) error {
+	if csvFile != "" && ndjsonFile != "" {
+		return fmt.Errorf("use either --csv or --ndjson, not both")
+	}
+
+	dimensions := req.Dimensions
+	searchType := req.SearchType
+
+	var (
+		rows     iter.Seq2[api.QueryRow, error]
+		shardErr error // a failed shard; the other shards' rows are still written
+	)
+	progress := output.NewProgress("Exporting:")
+	defer progress.Done()
+	if shard == "" {
+		rows = client.Rows(ctx, req, progress.Update)
+	} else {
+		var result *api.QueryResult
+		result, shardErr = api.QuerySharded(ctx, client, req, shard, concurrency, progress.Update)
+		if result == nil {
+			return shardErr
+		}
+		rows = result.All()
+	}
+
+	var (
+		written int
+		err     error
+		dest    = csvFile
+	)
+	switch {
+	case csvFile != "":
+		written, err = output.WriteQueryRowsCSV(csvFile, searchType, dimensions, rows)
+	case ndjsonFile == "-":
+		if _, err = output.WriteQueryRowsNDJSON(os.Stdout, dimensions, rows); err != nil {
+			return err
+		}
+		return shardErr
+	default:
+		dest = ndjsonFile
+		file, ferr := os.Create(ndjsonFile)
+		if ferr != nil {
+			return fmt.Errorf("could not create file: %w", ferr)
+		}
+		written, err = output.WriteQueryRowsNDJSON(file, dimensions, rows)
+		if cerr := file.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("could not write file: %w", cerr)
+		}
+	}
+	progress.Done()
+
+	if err == nil && shardErr != nil {
+		if written > 0 {
+			fmt.Printf("%s Wrote %d rows from the shards that finished to %s\n", output.Yellow("!"), written, dest)
+		}
+		return shardErr
+	}
+	if err != nil {
+		if written > 0 {
+			fmt.Printf("%s Kept %d rows written to %s before stopping\n", output.Yellow("!"), written, dest)
+		}
+		return err
 	}
 
-	dimension := strings.ToLower(parts[0])
-	expression := parts[1]
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Exported %d rows to %s\n", green("✓"), written, dest)
+	return nil
+}
 
-	// Validate dimension
+// parseDimensions parses a comma-separated dimension list like "query,page,device"
+func parseDimensions(s string) ([]string, error) {
 	validDimensions := map[string]bool{
-		"query": true, "page": true, "country": true, "device": true,
+		"query": true, "page": true, "country": true, "device": true, "date": true,
+	}
+
+	var dimensions []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		dim := strings.ToLower(strings.TrimSpace(part))
+		if dim == "" {
+			continue
+		}
+		if !validDimensions[dim] {
+			return nil, fmt.Errorf("invalid dimension: %s (valid: query, page, country, device, date)", dim)
+		}
+		if seen[dim] {
+			return nil, fmt.Errorf("duplicate dimension: %s", dim)
+		}
+		seen[dim] = true
+		dimensions = append(dimensions, dim)
 	}
-	if !validDimensions[dimension] {
-		return api.Filter{}, fmt.Errorf("invalid dimension: %s (valid: query, page, country, device)", dimension)
+
+	if len(dimensions) == 0 {
+		return nil, fmt.Errorf("no dimensions given")
+	}
+
+	return dimensions, nil
+}
+
+// resolveDateRange picks the date range from --start/--end, --days or the default
+func resolveDateRange(dates api.Dates, startDate, endDate string, days int) (api.DateRange, error) {
+	if startDate != "" || endDate != "" {
+		return dates.ParseRange(startDate, endDate)
 	}
 
-	// Determine operator based on expression
-	operator := "equals"
-	if strings.Contains(expression, "*") {
-		operator = "includingRegex"
-		// Convert glob to regex
-		expression = strings.ReplaceAll(expression, "*", ".*")
+	var r api.DateRange
+	if days > 0 {
+		r.Start, r.End = dates.RangeForDays(days)
+	} else {
+		r.Start, r.End = dates.DefaultRange()
+	}
+	return r, nil
+}
+
+// Data sources for --source
+const (
+	sourceAPI   = "api"
+	sourceLocal = "local"
+)
+
+// newQuerier returns the query backend selected by --source and a func to release it
+func newQuerier(ctx context.Context) (api.Querier, func(), error) {
+	if dataSource == sourceLocal {
+		if freshData {
+			return nil, nil, fmt.Errorf("--fresh is not available with --source=local: only final data is synced")
+		}
+		store, err := storage.Open(siteURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, func() { store.Close() }, nil
 	}
 
-	return api.Filter{
-		Dimension:  dimension,
-		Operator:   operator,
-		Expression: expression,
-	}, nil
+	client, err := api.NewClient(ctx, siteURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if noCache {
+		return client, func() {}, nil
+	}
+
+	c, err := cache.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	account := config.GetAccount()
+	if account == "" {
+		account = auth.ActiveSource()
+	}
+	return cache.Wrap(client, c, siteURL, account), func() {}, nil
+}
+
+// anchorDataDate probes the latest day with data and returns dates anchored
+// on it. When the dates given do not need it (see api.NeedsDataDate), nothing
+// is probed, the dates use the fixed delay and the returned freshness is nil.
+func anchorDataDate(ctx context.Context, client api.Querier, searchType string, needed bool) (api.Dates, *api.Freshness, error) {
+	if !needed {
+		return api.Dates{}, nil, nil
+	}
+	freshness, err := client.ProbeFreshness(ctx, searchType, freshData)
+	if err != nil {
+		return api.Dates{}, nil, err
+	}
+	dates, err := api.NewDates(freshness.LatestDate)
+	if err != nil {
+		return api.Dates{}, nil, err
+	}
+	return dates, freshness, nil
+}
+
+// dataState returns the data state to query, based on --fresh
+func dataState() string {
+	if freshData {
+		return api.DataStateAll
+	}
+	return api.DataStateFinal
+}
+
+// printFreshness notes when a range ending on end includes partial data
+func printFreshness(f *api.Freshness, end string) {
+	if f != nil && f.Partial && end >= f.FirstIncompleteDate {
+		fmt.Printf("%s Data from %s onward is partial and may still change\n", output.Yellow("!"), f.FirstIncompleteDate)
+	}
+}
+
+// parseFilters parses each --filter expression; all of them must match
+func parseFilters(exprs []string) ([]api.Filter, error) {
+	var filters []api.Filter
+	for _, expr := range exprs {
+		f, err := api.ParseFilter(expr)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f...)
+	}
+	return filters, nil
 }
@@ -1,9 +1,15 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/sivori/gsc-cli/internal/api"
 	"github.com/sivori/gsc-cli/internal/config"
 
 	"github.com/fatih/color"
@@ -12,9 +18,18 @@ import (
 
 var (
 	// Global flags
-	siteURL    string
-	jsonOutput bool
-	noColor    bool
+	siteURL     string
+	jsonOutput  bool
+	noColor     bool
+	freshData   bool
+	dataSource  string
+	timeout     time.Duration
+	noCache     bool
+	profile     string
+	accessToken string
+
+	// cancelTimeout releases the --timeout context
+	cancelTimeout context.CancelFunc
 
 	// Version info (set at build time)
 	Version = "dev"
@@ -38,23 +53,38 @@ To get started:
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			// Skip config init for auth and version commands
-			if cmd.Name() == "login" || cmd.Name() == "version" || cmd.Name() == "completion" {
-				return nil
-			}
-			if cmd.Parent() != nil && cmd.Parent().Name() == "auth" {
+			// Skip config init for version and completion
+			if cmd.Name() == "version" || cmd.Name() == "completion" {
 				return nil
 			}
 
 			if err := config.Init(); err != nil {
 				return fmt.Errorf("could not initialize config: %w", err)
 			}
+			if err := applyFlagSettings(cmd); err != nil {
+				return err
+			}
+
+			if timeout > 0 {
+				ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+				cancelTimeout = cancel
+				cmd.SetContext(ctx)
+			}
+
+			if err := config.SetProfile(profile); err != nil {
+				return err
+			}
+			config.SetAccessToken(accessToken)
 
 			// Apply global flags
 			if noColor {
 				color.NoColor = true
 			}
 
+			if dataSource != sourceAPI && dataSource != sourceLocal {
+				return fmt.Errorf("invalid --source: %s (valid: %s, %s)", dataSource, sourceAPI, sourceLocal)
+			}
+
 			// Override site URL if provided
 			if siteURL == "" {
 				siteURL = config.GetSiteURL()
@@ -68,6 +98,12 @@ To get started:
 	cmd.PersistentFlags().StringVarP(&siteURL, "site", "s", "", "Search Console site URL (e.g., sc-domain:example.com)")
 	cmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
 	cmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	cmd.PersistentFlags().BoolVar(&freshData, "fresh", false, "Include fresh (partial) data for the most recent days")
+	cmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Give up after this long, e.g. 30s or 5m (default: no limit)")
+	cmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Always query the API instead of reusing cached responses")
+	cmd.PersistentFlags().StringVar(&dataSource, "source", sourceAPI, "Where to read search data from (api, local)")
+	cmd.PersistentFlags().StringVar(&accessToken, "access-token", "", "OAuth access token to use as is (prefer GSC_ACCESS_TOKEN, which stays out of the process list)")
+	cmd.PersistentFlags().StringVar(&profile, "profile", config.DefaultProfile, "Named profile to use, each with its own login and default site")
 
 	// Add commands
 	cmd.AddCommand(newAuthCmd())
@@ -77,20 +113,119 @@ To get started:
 	cmd.AddCommand(newCompareCmd())
 	cmd.AddCommand(newDropsCmd())
 	cmd.AddCommand(newPagesCmd())
+	cmd.AddCommand(newSyncCmd())
+	cmd.AddCommand(newSQLCmd())
+	cmd.AddCommand(newCacheCmd())
 	cmd.AddCommand(newVersionCmd())
 	cmd.AddCommand(newCompletionCmd())
 
 	return cmd
 }
 
+// flagSettings maps global flags to the config settings that give their defaults
+var flagSettings = map[string]string{
+	"profile":  "profile",
+	"source":   "source",
+	"timeout":  "timeout",
+	"fresh":    "fresh",
+	"no-cache": "no_cache",
+	"no-color": "no_color",
+}
+
+// applyFlagSettings fills in global flags that were not given from the
+// environment and config files
+func applyFlagSettings(cmd *cobra.Command) error {
+	for flag, name := range flagSettings {
+		f := cmd.Flags().Lookup(flag)
+		if f == nil || f.Changed {
+			continue
+		}
+		value, source := config.Get(name)
+		if source == config.SourceDefault || value == "" {
+			continue
+		}
+		if err := f.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid %s from %s: %w", name, source, err)
+		}
+	}
+	return nil
+}
+
 // Execute runs the root command
 func Execute() {
-	if err := NewRootCmd().Execute(); err != nil {
+	// The first Ctrl-C cancels the running command so it can stop cleanly;
+	// a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := NewRootCmd().ExecuteContext(ctx)
+	if cancelTimeout != nil {
+		cancelTimeout()
+	}
+
+	if err != nil {
+		if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+			fmt.Fprintln(os.Stderr, color.RedString("Interrupted"))
+			os.Exit(130)
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			fmt.Fprintln(os.Stderr, color.RedString("Error: timed out after %s", timeout))
+			os.Exit(1)
+		}
+
 		fmt.Fprintln(os.Stderr, color.RedString("Error: %v", err))
+		if hint := errorHint(err); hint != "" {
+			fmt.Fprintln(os.Stderr, color.YellowString("Hint: %s", hint))
+		}
 		os.Exit(1)
 	}
 }
 
+// errorHint suggests how to fix a classified API error
+func errorHint(err error) string {
+	var (
+		quotaErr       *api.QuotaError
+		siteErr        *api.SiteNotFoundError
+		unverifiedErr  *api.NotVerifiedError
+		permissionErr  *api.PermissionError
+		invalidErr     *api.InvalidRequestError
+		tokenRevokeErr *api.TokenRevokedError
+	)
+
+	switch {
+	case errors.As(err, &quotaErr):
+		return "Search Console allows 1,200 queries per minute per site and per user, plus a daily load quota.\n" +
+			"      Wait a few minutes and try again, or query a shorter date range."
+	case errors.As(err, &siteErr):
+		hint := "URL-prefix properties need the scheme and trailing slash (https://example.com/);\n" +
+			"      domain properties use the sc-domain: prefix (sc-domain:example.com).\n" +
+			"      Run 'gsc sites' to list your properties."
+		if siteErr.Suggestion != "" {
+			hint = fmt.Sprintf("did you mean %s?\n      Use --site %s or 'gsc config set-site %s'.",
+				siteErr.Suggestion, siteErr.Suggestion, siteErr.Suggestion)
+		}
+		return hint
+	case errors.As(err, &unverifiedErr):
+		return "verify ownership of the site in Search Console, or ask an owner to add your account\n" +
+			"      (https://search.google.com/search-console/users)."
+	case errors.As(err, &permissionErr):
+		if permissionErr.Level != "" {
+			return fmt.Sprintf("your permission level on this site is %s; ask an owner for full or restricted access.", permissionErr.Level)
+		}
+		return "run 'gsc auth login' to re-authorize, and check that the Search Console API is enabled\n" +
+			"      for your Google Cloud project."
+	case errors.As(err, &invalidErr):
+		return "check the combination of --dimension, --filter and --type. Discover and Google News\n" +
+			"      do not support the query dimension or query filters."
+	case errors.As(err, &tokenRevokeErr):
+		return "run 'gsc auth login' to sign in again."
+	}
+	return ""
+}
+
 func newVersionCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
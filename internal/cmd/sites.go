@@ -17,12 +17,12 @@ func newSitesCmd() *cobra.Command {
 		Long:  "List all sites you have access to in Google Search Console.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// We need a dummy site to create the client, but ListSites doesn't use it
-			client, err := api.NewClientForSites()
+			client, err := api.NewClientForSites(cmd.Context())
 			if err != nil {
 				return err
 			}
 
-			sites, err := client.ListSites()
+			sites, err := client.ListSites(cmd.Context())
 			if err != nil {
 				return fmt.Errorf("could not list sites: %w", err)
 			}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sivori/gsc-cli/internal/output"
+	"github.com/sivori/gsc-cli/internal/storage"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func newSQLCmd() *cobra.Command {
+	var (
+		schema  bool
+		csvFile string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sql [statement]",
+		Short: "Run read-only SQL against the local database",
+		Long: `Run an ad-hoc, read-only SQL statement against the site's local database
+(see 'gsc sync'). The database is SQLite; REGEXP uses Go regex syntax.
+
+Besides the raw search_analytics table, the daily_queries and daily_pages
+views aggregate each day by query or by page, with CTR recomputed and
+position weighted by impressions.
+
+Examples:
+  gsc sql --schema
+  gsc sql "SELECT query, SUM(clicks) AS clicks FROM daily_queries
+           WHERE search_type = 'web' GROUP BY query ORDER BY clicks DESC LIMIT 20"
+  gsc sql "SELECT date, clicks, AVG(clicks) OVER (ORDER BY date ROWS 6 PRECEDING) AS avg_7d
+           FROM (SELECT date, SUM(clicks) AS clicks FROM daily_pages GROUP BY date)"
+  gsc sql "SELECT * FROM daily_pages WHERE page REGEXP '/blog/'" --csv blog.csv`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if siteURL == "" {
+				return fmt.Errorf("no site configured - run 'gsc auth login' or use --site")
+			}
+			if !schema && len(args) == 0 {
+				return fmt.Errorf("no SQL statement given - pass one as an argument or use --schema")
+			}
+
+			store, err := storage.OpenReadOnly(siteURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			if schema {
+				return printSchema(store)
+			}
+
+			rows, err := store.SQL(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			if csvFile != "" {
+				if err := output.WriteRecordsCSV(csvFile, rows.Columns, formatRecords(rows.Values, "")); err != nil {
+					return err
+				}
+				green := color.New(color.FgGreen).SprintFunc()
+				fmt.Printf("%s Exported %d rows to %s\n", green("✓"), len(rows.Values), csvFile)
+				return nil
+			}
+
+			if jsonOutput {
+				return output.PrintRecordsJSON(rows.Columns, rows.Values)
+			}
+
+			if len(rows.Values) == 0 {
+				fmt.Println("No rows.")
+				return nil
+			}
+
+			table := output.NewTable()
+			table.SetHeaders(rows.Columns...)
+			table.AppendBulk(formatRecords(rows.Values, output.Dim("NULL")))
+			table.Render()
+
+			fmt.Printf("\n%s rows\n", output.FormatCount(len(rows.Values)))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&schema, "schema", false, "Describe the tables and views")
+	cmd.Flags().StringVar(&csvFile, "csv", "", "Export to CSV file")
+
+	return cmd
+}
+
+// printSchema lists the tables and views of the local database with their columns
+func printSchema(store *storage.Store) error {
+	tables, err := store.Schema()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		columns := []string{"table", "type", "column", "column_type"}
+		var values [][]interface{}
+		for _, t := range tables {
+			for _, c := range t.Columns {
+				values = append(values, []interface{}{t.Name, t.Type, c.Name, c.Type})
+			}
+		}
+		return output.PrintRecordsJSON(columns, values)
+	}
+
+	fmt.Printf("Database: %s\n", store.Path())
+	for _, t := range tables {
+		fmt.Printf("\n%s %s\n", output.Bold(t.Name), output.Dim("("+t.Type+")"))
+		for _, c := range t.Columns {
+			colType := c.Type
+			if colType == "" {
+				colType = "ANY"
+			}
+			fmt.Printf("  %-12s %s\n", c.Name, strings.ToUpper(colType))
+		}
+	}
+	return nil
+}
+
+// formatRecords renders SQL values as strings, using null for NULL
+func formatRecords(values [][]interface{}, null string) [][]string {
+	records := make([][]string, len(values))
+	for i, row := range values {
+		record := make([]string, len(row))
+		for j, v := range row {
+			switch v := v.(type) {
+			case nil:
+				record[j] = null
+			case float64:
+				record[j] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				record[j] = fmt.Sprint(v)
+			}
+		}
+		records[i] = record
+	}
+	return records
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sivori/gsc-cli/internal/api"
+	"github.com/sivori/gsc-cli/internal/output"
+	"github.com/sivori/gsc-cli/internal/storage"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func newSyncCmd() *cobra.Command {
+	var (
+		startDate  string
+		endDate    string
+		searchType string
+		force      bool
+		workers    int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Backfill search data into a local database",
+		Long: `Download day-by-day query × page × country × device data into a local
+SQLite database, one per site, so history is kept beyond Search Console's
+16-month retention.
+
+The first run backfills the full 16 months. Later runs only fetch days that
+have not been synced yet. Only complete (final) data is stored. Several
+days are fetched at once (see --concurrency).
+
+Examples:
+  gsc sync                          # Backfill or catch up
+  gsc sync --start last-month       # Only sync last month
+  gsc sync --start 2025-01-01 --force  # Re-download days already synced
+  gsc sync --type discover          # Sync Discover data
+  gsc sync status                   # Show what has been synced`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if siteURL == "" {
+				return fmt.Errorf("no site configured - run 'gsc auth login' or use --site")
+			}
+
+			var err error
+			if searchType, err = api.NormalizeSearchType(searchType); err != nil {
+				return err
+			}
+
+			client, err := api.NewClient(ctx, siteURL)
+			if err != nil {
+				return err
+			}
+
+			// Only final data is stored, so anchor on the last complete day
+			freshness, err := client.ProbeFreshness(ctx, searchType, false)
+			if err != nil {
+				return err
+			}
+			dates, err := api.NewDates(freshness.LatestDate)
+			if err != nil {
+				return err
+			}
+
+			dateRange := api.DateRange{Start: dates.EarliestDataDate(), End: freshness.LatestDate}
+			if startDate != "" || endDate != "" {
+				if dateRange, err = dates.ParseRange(startDate, endDate); err != nil {
+					return err
+				}
+				// Days after the latest complete one have no final data yet
+				if dateRange.End > freshness.LatestDate {
+					if dateRange.Start > freshness.LatestDate {
+						return fmt.Errorf("nothing to sync: the latest complete day is %s", freshness.LatestDate)
+					}
+					fmt.Printf("%s Syncing up to %s, the latest complete day\n", output.Yellow("!"), freshness.LatestDate)
+					dateRange.End = freshness.LatestDate
+				}
+			}
+
+			store, err := storage.Open(siteURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			days, err := api.DaysBetween(dateRange.Start, dateRange.End)
+			if err != nil {
+				return err
+			}
+
+			synced, err := store.SyncedDays(searchType)
+			if err != nil {
+				return err
+			}
+
+			var pending []string
+			for _, day := range days {
+				if force || !synced[day] {
+					pending = append(pending, day)
+				}
+			}
+
+			fmt.Printf("Syncing %s (%s) into %s\n", output.Cyan(siteURL), searchType, store.Path())
+			fmt.Printf("Date range: %s to %s\n", dateRange.Start, dateRange.End)
+
+			if len(pending) == 0 {
+				green := color.New(color.FgGreen).SprintFunc()
+				fmt.Printf("%s Already up to date\n", green("✓"))
+				return nil
+			}
+			fmt.Printf("Days to sync: %d\n\n", len(pending))
+
+			dimensions := []string{"query", "page", "country", "device"}
+			if !api.HasQueryData(searchType) {
+				dimensions = dimensions[1:]
+			}
+
+			runID, err := store.StartRun(searchType, dateRange.Start, dateRange.End)
+			if err != nil {
+				return err
+			}
+
+			shards := make([]api.Shard, len(pending))
+			for i, day := range pending {
+				shards[i] = api.Shard{Label: day, Request: api.QueryRequest{
+					StartDate:  day,
+					EndDate:    day,
+					Dimensions: dimensions,
+					SearchType: searchType,
+					DataState:  api.DataStateFinal,
+				}}
+			}
+
+			// Days finish out of order; each is stored as soon as it is complete
+			var syncedDays, syncedRows int
+			runErr := api.FetchShards(ctx, client, shards, workers, nil, func(shard api.Shard, result *api.QueryResult) error {
+				err := store.ReplaceDay(searchType, shard.Label, freshness.IncompleteFrom(), result.Rows)
+				if errors.Is(err, storage.ErrIncompleteDay) {
+					fmt.Printf("  %s  no final data yet\n", shard.Label)
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+
+				syncedDays++
+				syncedRows += len(result.Rows)
+				fmt.Printf("  %s  %s rows\n", shard.Label, output.FormatCount(len(result.Rows)))
+				return nil
+			})
+			if runErr != nil && ctx.Err() == nil {
+				runErr = fmt.Errorf("could not fetch %w", runErr)
+			}
+
+			if err := store.FinishRun(runID, syncedDays, syncedRows, runErr); err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				fmt.Printf("\n%s Stopped after syncing %d days (%s rows) - rerun to resume\n",
+					output.Yellow("!"), syncedDays, output.FormatCount(syncedRows))
+				return ctx.Err()
+			}
+
+			var quotaErr *api.QuotaError
+			if errors.As(runErr, &quotaErr) {
+				return fmt.Errorf("sync paused after %d days - rerun later to resume: %w", syncedDays, runErr)
+			}
+			if runErr != nil {
+				return fmt.Errorf("sync stopped after %d days (rerun to resume): %w", syncedDays, runErr)
+			}
+
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("\n%s Synced %d days (%s rows)\n", green("✓"), syncedDays, output.FormatCount(syncedRows))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&startDate, "start", "", "Start date or expression (default: 16 months ago)")
+	cmd.Flags().StringVar(&endDate, "end", "", "End date or expression (default: latest complete day)")
+	cmd.Flags().StringVar(&searchType, "type", "web", "Search type (web, image, video, news, discover, googleNews)")
+	cmd.Flags().BoolVar(&force, "force", false, "Re-download days that were already synced")
+	cmd.Flags().IntVar(&workers, "concurrency", api.DefaultConcurrency, "Days to fetch at once")
+
+	cmd.AddCommand(newSyncStatusCmd())
+
+	return cmd
+}
+
+func newSyncStatusCmd() *cobra.Command {
+	var searchType string
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show what has been synced locally",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if siteURL == "" {
+				return fmt.Errorf("no site configured - run 'gsc auth login' or use --site")
+			}
+
+			var err error
+			if searchType, err = api.NormalizeSearchType(searchType); err != nil {
+				return err
+			}
+
+			store, err := storage.Open(siteURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			status, err := store.Status(searchType)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Local data for %s (%s)\n", output.Cyan(siteURL), searchType)
+			fmt.Printf("  Database:  %s\n", store.Path())
+
+			if status.Days == 0 {
+				fmt.Println("  Nothing synced yet - run 'gsc sync'")
+				return nil
+			}
+
+			fmt.Printf("  Days:      %d (%s to %s)\n", status.Days, status.FirstDate, status.LastDate)
+			fmt.Printf("  Rows:      %s\n", output.FormatCount(int(status.Rows)))
+			if status.LastRunAt != "" {
+				fmt.Printf("  Last sync: %s (%s)\n", status.LastRunAt, status.LastRunInfo)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&searchType, "type", "web", "Search type (web, image, video, news, discover, googleNews)")
+
+	return cmd
+}
@@ -4,77 +4,249 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 
 	"github.com/spf13/viper"
 )
 
 const (
-	configDir  = ".config/gsc-cli"
+	configName = "gsc-cli"
 	configFile = "config"
 	configType = "yaml"
 )
 
-// Init initializes the configuration system
-func Init() error {
-	path, err := configPath()
-	if err != nil {
-		return err
-	}
+// DefaultProfile is the profile used when --profile is not given. Its
+// settings live at the top level of the config file.
+const DefaultProfile = "default"
 
-	if err := os.MkdirAll(path, 0700); err != nil {
-		return fmt.Errorf("could not create config directory: %w", err)
-	}
+// profile is the active profile
+var profile = DefaultProfile
 
-	viper.SetConfigName(configFile)
-	viper.SetConfigType(configType)
-	viper.AddConfigPath(path)
+// accessToken is the access token given with --access-token. It is never
+// written to the config file.
+var accessToken string
 
-	// Defaults
-	viper.SetDefault("site_url", "")
-	viper.SetDefault("client_secret_path", "")
+// validProfile matches allowed profile names. Viper lowercases keys, so
+// names are lowercase only.
+var validProfile = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
 
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			configFilePath := filepath.Join(path, configFile+"."+configType)
-			if err := viper.SafeWriteConfigAs(configFilePath); err != nil {
-				return fmt.Errorf("could not create config file: %w", err)
-			}
-		} else {
-			return fmt.Errorf("could not read config file: %w", err)
-		}
+// SetProfile selects the profile whose settings are read and written
+func SetProfile(name string) error {
+	if name == "" {
+		name = DefaultProfile
 	}
-
+	if !validProfile.MatchString(name) {
+		return fmt.Errorf("invalid profile name: %s (use lowercase letters, digits, - and _)", name)
+	}
+	profile = name
 	return nil
 }
 
+// Profile returns the active profile
+func Profile() string {
+	return profile
+}
+
+// key returns the config key for a setting of the given profile
+func key(profile, name string) string {
+	if profile == DefaultProfile {
+		return name
+	}
+	return "profiles." + profile + "." + name
+}
+
+// Init loads the configuration from the environment, the closest
+// .gsc.yaml and the user config file. Nothing is written until a setting
+// is changed.
+func Init() error {
+	return load()
+}
+
+// configPath returns the user config directory, under XDG_CONFIG_HOME if set
 func configPath() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" && filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, configName), nil
+	}
+
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("could not get home directory: %w", err)
 	}
-	return filepath.Join(home, configDir), nil
+	return filepath.Join(home, ".config", configName), nil
+}
+
+// DataDir returns the directory holding local data such as synced databases
+func DataDir() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(path, "data"), nil
+}
+
+// CacheDir returns the directory holding cached API responses
+func CacheDir() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(path, "cache"), nil
+}
+
+// TokenFile returns the path of the encrypted token file
+func TokenFile() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(path, "tokens.enc"), nil
 }
 
 // GetSiteURL returns the configured Search Console site URL
 func GetSiteURL() string {
-	return viper.GetString("site_url")
+	return getString("site_url")
 }
 
 // SetSiteURL sets the Search Console site URL
 func SetSiteURL(url string) error {
-	viper.Set("site_url", url)
-	return viper.WriteConfig()
+	return set("site_url", url)
 }
 
 // GetClientSecretPath returns the path to the OAuth client secret file
 func GetClientSecretPath() string {
-	return viper.GetString("client_secret_path")
+	return getString("client_secret_path")
 }
 
 // SetClientSecretPath sets the path to the OAuth client secret file
 func SetClientSecretPath(path string) error {
-	viper.Set("client_secret_path", path)
-	return viper.WriteConfig()
+	return set("client_secret_path", path)
+}
+
+// GetServiceAccountKey returns the path to the service account JSON key, if any
+func GetServiceAccountKey() string {
+	return getString("service_account_key")
+}
+
+// GetServiceAccountSubject returns the user a service account impersonates, if any
+func GetServiceAccountSubject() string {
+	return getString("service_account_subject")
+}
+
+// SetServiceAccount sets the service account key path and delegation subject.
+// Empty values switch back to OAuth login.
+func SetServiceAccount(keyPath, subject string) error {
+	if err := set("service_account_key", keyPath); err != nil {
+		return err
+	}
+	return set("service_account_subject", subject)
+}
+
+// GetAccessToken returns the access token given with --access-token or
+// GSC_ACCESS_TOKEN, if any
+func GetAccessToken() string {
+	if accessToken != "" {
+		return accessToken
+	}
+	return os.Getenv("GSC_ACCESS_TOKEN")
+}
+
+// SetAccessToken sets the access token for this run only
+func SetAccessToken(token string) {
+	accessToken = token
+}
+
+// GetUseADC returns whether the profile uses Application Default Credentials
+func GetUseADC() bool {
+	return getBool("use_adc")
+}
+
+// SetUseADC sets whether the profile uses Application Default Credentials
+func SetUseADC(use bool) error {
+	return set("use_adc", use)
+}
+
+// GetTokenStore returns where OAuth tokens are kept: auto, keyring, file or env
+func GetTokenStore() string {
+	return getString("token_store")
+}
+
+// SetTokenStore sets where OAuth tokens are kept
+func SetTokenStore(name string) error {
+	return set("token_store", name)
+}
+
+// GetAccount returns the email of the account the profile is signed in as.
+// Like the scopes, it is recorded at login and only kept in the user config file.
+func GetAccount() string {
+	return userConfig.GetString(key(profile, "account"))
+}
+
+// SetAccount records the email of the account the profile is signed in as
+func SetAccount(email string) error {
+	return setKey(key(profile, "account"), email)
+}
+
+// GetScopes returns the OAuth scopes the profile's login was granted, or
+// nil if they were not recorded
+func GetScopes() []string {
+	return userConfig.GetStringSlice(key(profile, "scopes"))
+}
+
+// SetScopes records the OAuth scopes the profile's login was granted
+func SetScopes(scopes []string) error {
+	return setKey(key(profile, "scopes"), scopes)
+}
+
+// GetNoBrowser returns whether the profile logged in with --no-browser, so
+// later consent prompts use the same flow
+func GetNoBrowser() bool {
+	return userConfig.GetBool(key(profile, "no_browser"))
+}
+
+// SetNoBrowser records whether the profile logged in with --no-browser
+func SetNoBrowser(noBrowser bool) error {
+	return setKey(key(profile, "no_browser"), noBrowser)
+}
+
+// ProfileInfo summarizes the settings of one profile
+type ProfileInfo struct {
+	Name              string
+	SiteURL           string
+	Account           string
+	ServiceAccountKey string
+}
+
+// Profiles returns every profile in the config files, the default one first
+func Profiles() []ProfileInfo {
+	names := []string{}
+	seen := map[string]bool{DefaultProfile: true}
+	for _, v := range []*viper.Viper{userConfig, projectConfig} {
+		if v == nil {
+			continue
+		}
+		for name := range v.GetStringMap("profiles") {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	names = append([]string{DefaultProfile}, names...)
+
+	profiles := make([]ProfileInfo, len(names))
+	for i, name := range names {
+		site, _ := getFor(name, "site_url")
+		keyPath, _ := getFor(name, "service_account_key")
+		profiles[i] = ProfileInfo{
+			Name:              name,
+			SiteURL:           site,
+			Account:           userConfig.GetString(key(name, "account")),
+			ServiceAccountKey: keyPath,
+		}
+	}
+	return profiles
 }
 
 // IsConfigured returns true if the CLI has been configured
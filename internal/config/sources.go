@@ -0,0 +1,263 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ProjectFile is the per-repository config file, found by walking up from
+// the working directory
+const ProjectFile = ".gsc.yaml"
+
+// SourceDefault is the source of a setting nobody set
+const SourceDefault = "default"
+
+// Setting describes a config setting. Settings are looked up in the
+// environment, then the project file, then the user config file.
+type Setting struct {
+	Name    string   // key in config files
+	Env     []string // environment variables overriding it; the first one set wins
+	Default string
+	Profile bool // whether each profile has its own value
+	// Credential settings pick how gsc signs in. A project file is usually
+	// checked in and shared, so they are never read from it.
+	Credential bool
+	// Path settings hold a file path; a relative path in a config file is
+	// relative to that file's directory
+	Path bool
+}
+
+// Settings lists every config setting
+var Settings = []Setting{
+	{Name: "site_url", Env: []string{"GSC_SITE", "GSC_SITE_URL"}, Profile: true},
+	{Name: "client_secret_path", Env: []string{"GSC_CLIENT_SECRET_PATH"}, Profile: true, Credential: true, Path: true},
+	{Name: "service_account_key", Env: []string{"GSC_SERVICE_ACCOUNT_KEY"}, Profile: true, Credential: true, Path: true},
+	{Name: "service_account_subject", Env: []string{"GSC_SERVICE_ACCOUNT_SUBJECT"}, Profile: true, Credential: true},
+	{Name: "use_adc", Env: []string{"GSC_USE_ADC"}, Default: "false", Profile: true, Credential: true},
+	{Name: "token_store", Env: []string{"GSC_TOKEN_STORE"}, Default: "auto", Credential: true},
+	{Name: "profile", Env: []string{"GSC_PROFILE"}, Default: DefaultProfile},
+	{Name: "source", Env: []string{"GSC_SOURCE"}, Default: "api"},
+	{Name: "timeout", Env: []string{"GSC_TIMEOUT"}},
+	{Name: "fresh", Env: []string{"GSC_FRESH"}, Default: "false"},
+	{Name: "no_cache", Env: []string{"GSC_NO_CACHE"}, Default: "false"},
+	{Name: "no_color", Env: []string{"GSC_NO_COLOR"}, Default: "false"},
+}
+
+// Config layers. userConfig is the only one ever written, so values from
+// the environment or a project file never end up in the user's file.
+var (
+	envConfig     = viper.New()
+	projectConfig *viper.Viper
+	projectFile   string
+	userConfig    = viper.New()
+	userFile      string
+)
+
+// load reads the environment, the project file and the user config file
+func load() error {
+	dir, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	userFile = filepath.Join(dir, configFile+"."+configType)
+	userConfig = viper.New()
+	userConfig.SetConfigFile(userFile)
+	if err := userConfig.ReadInConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+
+	projectConfig, projectFile = nil, findProjectFile()
+	if projectFile != "" {
+		projectConfig = viper.New()
+		projectConfig.SetConfigFile(projectFile)
+		projectConfig.SetConfigType(configType)
+		if err := projectConfig.ReadInConfig(); err != nil {
+			return fmt.Errorf("could not read %s: %w", projectFile, err)
+		}
+	}
+
+	envConfig = viper.New()
+	for _, s := range Settings {
+		if err := envConfig.BindEnv(append([]string{s.Name}, s.Env...)...); err != nil {
+			return fmt.Errorf("could not bind environment for %s: %w", s.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// findProjectFile returns the closest .gsc.yaml in the working directory or
+// its parents, or ""
+func findProjectFile() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// findSetting returns the named setting
+func findSetting(name string) Setting {
+	for _, s := range Settings {
+		if s.Name == name {
+			return s
+		}
+	}
+	return Setting{Name: name}
+}
+
+// lookup finds the layer holding a setting for a profile. It returns the
+// layer and key to read, and the source to report; a nil layer means the
+// default applies.
+func lookup(profile, name string) (*viper.Viper, string, string) {
+	s := findSetting(name)
+
+	if envConfig.IsSet(name) {
+		for _, env := range s.Env {
+			if os.Getenv(env) != "" {
+				return envConfig, name, "$" + env
+			}
+		}
+	}
+
+	k := name
+	if s.Profile {
+		k = key(profile, name)
+	}
+
+	// A project file's top-level settings apply to every profile
+	if projectConfig != nil && !s.Credential {
+		if projectConfig.IsSet(k) {
+			return projectConfig, k, projectFile
+		}
+		if projectConfig.IsSet(name) {
+			return projectConfig, name, projectFile
+		}
+	}
+
+	if userConfig.IsSet(k) {
+		return userConfig, k, userFile
+	}
+
+	return nil, "", SourceDefault
+}
+
+// Get returns a setting's effective value for the active profile and where
+// it came from: an environment variable, a config file path, or "default"
+func Get(name string) (value, source string) {
+	return getFor(profile, name)
+}
+
+// getFor returns a setting's effective value for a profile and its source
+func getFor(profile, name string) (string, string) {
+	s := findSetting(name)
+	v, k, source := lookup(profile, name)
+	if v == nil {
+		return s.Default, source
+	}
+
+	value := v.GetString(k)
+	if s.Path && value != "" && !filepath.IsAbs(value) && v != envConfig {
+		value = filepath.Join(filepath.Dir(source), value)
+	}
+	return value, source
+}
+
+// getString returns a setting's effective value for the active profile
+func getString(name string) string {
+	value, _ := Get(name)
+	return value
+}
+
+// getBool returns a boolean setting's effective value for the active profile
+func getBool(name string) bool {
+	b, _ := strconv.ParseBool(getString(name))
+	return b
+}
+
+// set writes a setting for the active profile to the user config file
+func set(name string, value interface{}) error {
+	k := name
+	if findSetting(name).Profile {
+		k = key(profile, name)
+	}
+	return setKey(k, value)
+}
+
+// setKey writes a raw key to the user config file, creating it if needed
+func setKey(k string, value interface{}) error {
+	if userFile == "" {
+		if err := load(); err != nil {
+			return err
+		}
+	}
+
+	userConfig.Set(k, value)
+
+	if err := os.MkdirAll(filepath.Dir(userFile), 0700); err != nil {
+		return fmt.Errorf("could not create config directory: %w", err)
+	}
+	return userConfig.WriteConfigAs(userFile)
+}
+
+// Value is the effective value of a setting and where it came from
+type Value struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// Values returns the effective value of every setting for the active profile
+func Values() []Value {
+	values := make([]Value, len(Settings))
+	for i, s := range Settings {
+		value, source := Get(s.Name)
+		values[i] = Value{Name: s.Name, Value: value, Source: source}
+	}
+	return values
+}
+
+// IgnoredProjectSettings returns the credential settings the project file
+// sets, which are ignored there, as config keys
+func IgnoredProjectSettings() []string {
+	if projectConfig == nil {
+		return nil
+	}
+
+	var ignored []string
+	for _, k := range projectConfig.AllKeys() {
+		name := k
+		if rest, ok := strings.CutPrefix(k, "profiles."); ok {
+			_, name, _ = strings.Cut(rest, ".")
+		}
+		if findSetting(name).Credential {
+			ignored = append(ignored, k)
+		}
+	}
+	sort.Strings(ignored)
+	return ignored
+}
+
+// Files returns the user config file and the project file in use, if any
+func Files() (user, project string) {
+	return userFile, projectFile
+}
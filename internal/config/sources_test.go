@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// setupConfig writes a user config and a project file, moves into a
+// directory below the project and loads the config. It returns the user
+// config directory.
+func setupConfig(t *testing.T, user, project string) string {
+	t.Helper()
+
+	xdg := t.TempDir()
+	userDir := filepath.Join(xdg, configName)
+	if err := os.MkdirAll(userDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(userDir, configFile+"."+configType), []byte(user), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, ProjectFile), []byte(project), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(projectDir, "sub")
+	if err := os.Mkdir(sub, 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(sub)
+
+	for _, s := range Settings {
+		for _, env := range s.Env {
+			t.Setenv(env, "")
+		}
+	}
+	t.Cleanup(func() { profile = DefaultProfile })
+
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	return userDir
+}
+
+func TestProjectFileIgnoresCredentials(t *testing.T) {
+	userDir := setupConfig(t, `
+client_secret_path: secrets/client.json
+token_store: keyring
+`, `
+site_url: sc-domain:example.com
+client_secret_path: /tmp/evil.json
+token_store: file
+use_adc: true
+profiles:
+  work:
+    service_account_key: key.json
+`)
+
+	if value, source := Get("site_url"); value != "sc-domain:example.com" || filepath.Base(source) != ProjectFile {
+		t.Errorf("site_url = %q from %s, want the project file's", value, source)
+	}
+
+	// Relative paths are relative to the file they are set in
+	want := filepath.Join(userDir, "secrets", "client.json")
+	if value, source := Get("client_secret_path"); value != want || filepath.Base(source) != configFile+"."+configType {
+		t.Errorf("client_secret_path = %q from %s, want %q from the user config", value, source, want)
+	}
+	if got := GetTokenStore(); got != "keyring" {
+		t.Errorf("token_store = %q, want keyring", got)
+	}
+	if GetUseADC() {
+		t.Error("use_adc was read from the project file")
+	}
+
+	if err := SetProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetServiceAccountKey(); got != "" {
+		t.Errorf("service_account_key = %q, want it ignored", got)
+	}
+
+	ignored := IgnoredProjectSettings()
+	wantIgnored := []string{"client_secret_path", "profiles.work.service_account_key", "token_store", "use_adc"}
+	if !slices.Equal(ignored, wantIgnored) {
+		t.Errorf("IgnoredProjectSettings() = %v, want %v", ignored, wantIgnored)
+	}
+}
+
+func TestEnvironmentPathsAreNotResolved(t *testing.T) {
+	setupConfig(t, "", "")
+	t.Setenv("GSC_CLIENT_SECRET_PATH", "client.json")
+
+	if got := GetClientSecretPath(); got != "client.json" {
+		t.Errorf("client_secret_path = %q, want it as given", got)
+	}
+}
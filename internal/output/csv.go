@@ -3,6 +3,7 @@ package output
 import (
 	"encoding/csv"
 	"fmt"
+	"iter"
 	"os"
 	"strconv"
 
@@ -11,9 +12,16 @@ import (
 
 // WriteQueryResultCSV writes query results to a CSV file
 func WriteQueryResultCSV(filename string, result *api.QueryResult, dimensions []string) error {
+	_, err := WriteQueryRowsCSV(filename, result.SearchType, dimensions, result.All())
+	return err
+}
+
+// WriteQueryRowsCSV streams rows to a CSV file as they arrive and returns
+// how many were written. Rows written before an error are kept.
+func WriteQueryRowsCSV(filename, searchType string, dimensions []string, rows iter.Seq2[api.QueryRow, error]) (int, error) {
 	file, err := os.Create(filename)
 	if err != nil {
-		return fmt.Errorf("could not create file: %w", err)
+		return 0, fmt.Errorf("could not create file: %w", err)
 	}
 	defer file.Close()
 
@@ -21,51 +29,57 @@ func WriteQueryResultCSV(filename string, result *api.QueryResult, dimensions []
 	defer writer.Flush()
 
 	// Build header based on dimensions
-	header := []string{}
-	for _, dim := range dimensions {
-		switch dim {
-		case "query":
-			header = append(header, "Query")
-		case "page":
-			header = append(header, "Page")
-		case "country":
-			header = append(header, "Country")
-		case "device":
-			header = append(header, "Device")
-		case "date":
-			header = append(header, "Date")
-		}
-	}
-	header = append(header, "Clicks", "Impressions", "CTR", "Position")
+	header := dimensionHeaders(dimensions)
+	header = append(header, "Clicks", "Impressions", "CTR", "Position", "Search Type")
 
 	if err := writer.Write(header); err != nil {
-		return fmt.Errorf("could not write header: %w", err)
+		return 0, fmt.Errorf("could not write header: %w", err)
 	}
 
 	// Write rows
-	for _, row := range result.Rows {
+	written := 0
+	for row, err := range rows {
+		if err != nil {
+			return written, err
+		}
+
 		record := []string{}
 		for _, dim := range dimensions {
-			switch dim {
-			case "query":
-				record = append(record, row.Query)
-			case "page":
-				record = append(record, row.Page)
-			case "country":
-				record = append(record, row.Country)
-			case "device":
-				record = append(record, row.Device)
-			case "date":
-				record = append(record, row.Date)
-			}
+			record = append(record, row.Dimension(dim))
 		}
 		record = append(record,
 			strconv.FormatFloat(row.Clicks, 'f', 0, 64),
 			strconv.FormatFloat(row.Impressions, 'f', 0, 64),
 			strconv.FormatFloat(row.CTR*100, 'f', 2, 64)+"%",
 			strconv.FormatFloat(row.Position, 'f', 1, 64),
+			searchType,
 		)
 
+		if err := writer.Write(record); err != nil {
+			return written, fmt.Errorf("could not write row: %w", err)
+		}
+		written++
+	}
+
+	return written, nil
+}
+
+// WriteRecordsCSV writes arbitrary records under the given header to a CSV file
+func WriteRecordsCSV(filename string, header []string, records [][]string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("could not create file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("could not write header: %w", err)
+	}
+
+	for _, record := range records {
 		if err := writer.Write(record); err != nil {
 			return fmt.Errorf("could not write row: %w", err)
 		}
@@ -76,7 +90,7 @@ func WriteQueryResultCSV(filename string, result *api.QueryResult, dimensions []
 
 // ComparisonRow represents a comparison between two periods
 type ComparisonRow struct {
-	Query               string
+	Keys                []string // dimension values, in the compared order
 	CurrentClicks       float64
 	PreviousClicks      float64
 	ClicksDelta         float64
@@ -91,7 +105,7 @@ type ComparisonRow struct {
 }
 
 // WriteComparisonCSV writes comparison results to a CSV file
-func WriteComparisonCSV(filename string, rows []ComparisonRow) error {
+func WriteComparisonCSV(filename, searchType string, dimensions []string, rows []ComparisonRow) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("could not create file: %w", err)
@@ -101,32 +115,34 @@ func WriteComparisonCSV(filename string, rows []ComparisonRow) error {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	header := []string{
-		"Query",
+	header := dimensionHeaders(dimensions)
+	header = append(header,
 		"Clicks (Current)", "Clicks (Previous)", "Clicks Delta", "Clicks %",
 		"Impressions (Current)", "Impressions (Previous)", "Impressions Delta", "Impressions %",
 		"Position (Current)", "Position (Previous)", "Position Delta",
-	}
+		"Search Type",
+	)
 
 	if err := writer.Write(header); err != nil {
 		return fmt.Errorf("could not write header: %w", err)
 	}
 
 	for _, row := range rows {
-		record := []string{
-			row.Query,
+		record := append([]string{}, row.Keys...)
+		record = append(record,
 			strconv.FormatFloat(row.CurrentClicks, 'f', 0, 64),
 			strconv.FormatFloat(row.PreviousClicks, 'f', 0, 64),
 			strconv.FormatFloat(row.ClicksDelta, 'f', 0, 64),
-			strconv.FormatFloat(row.ClicksPercent, 'f', 1, 64) + "%",
+			strconv.FormatFloat(row.ClicksPercent, 'f', 1, 64)+"%",
 			strconv.FormatFloat(row.CurrentImpressions, 'f', 0, 64),
 			strconv.FormatFloat(row.PreviousImpressions, 'f', 0, 64),
 			strconv.FormatFloat(row.ImpressionsDelta, 'f', 0, 64),
-			strconv.FormatFloat(row.ImpressionsPercent, 'f', 1, 64) + "%",
+			strconv.FormatFloat(row.ImpressionsPercent, 'f', 1, 64)+"%",
 			strconv.FormatFloat(row.CurrentPosition, 'f', 1, 64),
 			strconv.FormatFloat(row.PreviousPosition, 'f', 1, 64),
 			strconv.FormatFloat(row.PositionDelta, 'f', 1, 64),
-		}
+			searchType,
+		)
 
 		if err := writer.Write(record); err != nil {
 			return fmt.Errorf("could not write row: %w", err)
@@ -148,7 +164,7 @@ type DropsRow struct {
 }
 
 // WriteDropsCSV writes ranking drops to a CSV file
-func WriteDropsCSV(filename string, rows []DropsRow) error {
+func WriteDropsCSV(filename, searchType string, rows []DropsRow) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("could not create file: %w", err)
@@ -164,6 +180,7 @@ func WriteDropsCSV(filename string, rows []DropsRow) error {
 		"Current Position", "Previous Position",
 		"Current Clicks", "Previous Clicks",
 		"Current Impressions",
+		"Search Type",
 	}
 
 	if err := writer.Write(header); err != nil {
@@ -179,6 +196,7 @@ func WriteDropsCSV(filename string, rows []DropsRow) error {
 			strconv.FormatFloat(row.CurrentClicks, 'f', 0, 64),
 			strconv.FormatFloat(row.PreviousClicks, 'f', 0, 64),
 			strconv.FormatFloat(row.CurrentImpressions, 'f', 0, 64),
+			searchType,
 		}
 
 		if err := writer.Write(record); err != nil {
@@ -188,3 +206,23 @@ func WriteDropsCSV(filename string, rows []DropsRow) error {
 
 	return nil
 }
+
+// dimensionHeaders returns the CSV column titles for the given dimensions
+func dimensionHeaders(dimensions []string) []string {
+	header := make([]string, 0, len(dimensions))
+	for _, dim := range dimensions {
+		switch dim {
+		case "query":
+			header = append(header, "Query")
+		case "page":
+			header = append(header, "Page")
+		case "country":
+			header = append(header, "Country")
+		case "device":
+			header = append(header, "Device")
+		case "date":
+			header = append(header, "Date")
+		}
+	}
+	return header
+}
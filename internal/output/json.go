@@ -1,6 +1,7 @@
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -10,41 +11,58 @@ import (
 
 // JSONQueryResult represents query results in JSON format
 type JSONQueryResult struct {
-	StartDate string         `json:"start_date"`
-	EndDate   string         `json:"end_date"`
-	TotalRows int            `json:"total_rows"`
-	Rows      []JSONQueryRow `json:"rows"`
+	SearchType string         `json:"search_type"`
+	StartDate  string         `json:"start_date"`
+	EndDate    string         `json:"end_date"`
+	Dimensions []string       `json:"dimensions"`
+	TotalRows  int            `json:"total_rows"`
+	Incomplete string         `json:"first_incomplete_date,omitempty"`
+	Rows       []JSONQueryRow `json:"rows"`
 }
 
-// JSONQueryRow represents a single query row in JSON format
+// JSONQueryRow represents a single query row in JSON format.
+// Dimension values are emitted first, in the requested order.
 type JSONQueryRow struct {
-	Query       string  `json:"query,omitempty"`
-	Page        string  `json:"page,omitempty"`
-	Country     string  `json:"country,omitempty"`
-	Device      string  `json:"device,omitempty"`
-	Date        string  `json:"date,omitempty"`
-	Clicks      float64 `json:"clicks"`
-	Impressions float64 `json:"impressions"`
-	CTR         float64 `json:"ctr"`
-	Position    float64 `json:"position"`
+	Dimensions  []string
+	Keys        []string
+	Clicks      float64
+	Impressions float64
+	CTR         float64
+	Position    float64
+}
+
+// MarshalJSON encodes the row as a flat object keyed by dimension name
+func (r JSONQueryRow) MarshalJSON() ([]byte, error) {
+	obj := dimensionFields(r.Dimensions, r.Keys)
+	obj = append(obj,
+		jsonField{"clicks", r.Clicks},
+		jsonField{"impressions", r.Impressions},
+		jsonField{"ctr", r.CTR},
+		jsonField{"position", r.Position},
+	)
+	return obj.MarshalJSON()
 }
 
 // PrintQueryResultJSON prints query results as JSON
-func PrintQueryResultJSON(result *api.QueryResult) error {
+func PrintQueryResultJSON(result *api.QueryResult, dimensions []string) error {
 	output := JSONQueryResult{
-		StartDate: result.StartDate,
-		EndDate:   result.EndDate,
-		TotalRows: result.TotalRows,
-		Rows:      make([]JSONQueryRow, len(result.Rows)),
+		SearchType: result.SearchType,
+		StartDate:  result.StartDate,
+		EndDate:    result.EndDate,
+		Dimensions: dimensions,
+		TotalRows:  result.TotalRows,
+		Incomplete: result.FirstIncompleteDate,
+		Rows:       make([]JSONQueryRow, len(result.Rows)),
 	}
 
 	for i, row := range result.Rows {
+		keys := make([]string, len(dimensions))
+		for j, dim := range dimensions {
+			keys[j] = row.Dimension(dim)
+		}
 		output.Rows[i] = JSONQueryRow{
-			Query:       row.Query,
-			Page:        row.Page,
-			Country:     row.Country,
-			Device:      row.Device,
-			Date:        row.Date,
+			Dimensions:  dimensions,
+			Keys:        keys,
 			Clicks:      row.Clicks,
 			Impressions: row.Impressions,
 			CTR:         row.CTR,
@@ -57,6 +75,8 @@ func PrintQueryResultJSON(result *api.QueryResult) error {
 
 // JSONComparisonResult represents comparison results in JSON format
 type JSONComparisonResult struct {
+	SearchType     string              `json:"search_type"`
+	Dimensions     []string            `json:"dimensions"`
 	CurrentPeriod  Period              `json:"current_period"`
 	PreviousPeriod Period              `json:"previous_period"`
 	Rows           []JSONComparisonRow `json:"rows"`
@@ -68,25 +88,48 @@ type Period struct {
 	End   string `json:"end"`
 }
 
-// JSONComparisonRow represents a comparison row in JSON format
+// JSONComparisonRow represents a comparison row in JSON format.
+// Dimension values are emitted first, in the compared order.
 type JSONComparisonRow struct {
-	Query               string  `json:"query"`
-	CurrentClicks       float64 `json:"current_clicks"`
-	PreviousClicks      float64 `json:"previous_clicks"`
-	ClicksDelta         float64 `json:"clicks_delta"`
-	ClicksPercent       float64 `json:"clicks_percent"`
-	CurrentImpressions  float64 `json:"current_impressions"`
-	PreviousImpressions float64 `json:"previous_impressions"`
-	ImpressionsDelta    float64 `json:"impressions_delta"`
-	ImpressionsPercent  float64 `json:"impressions_percent"`
-	CurrentPosition     float64 `json:"current_position"`
-	PreviousPosition    float64 `json:"previous_position"`
-	PositionDelta       float64 `json:"position_delta"`
+	Dimensions          []string
+	Keys                []string
+	CurrentClicks       float64
+	PreviousClicks      float64
+	ClicksDelta         float64
+	ClicksPercent       float64
+	CurrentImpressions  float64
+	PreviousImpressions float64
+	ImpressionsDelta    float64
+	ImpressionsPercent  float64
+	CurrentPosition     float64
+	PreviousPosition    float64
+	PositionDelta       float64
+}
+
+// MarshalJSON encodes the row as a flat object keyed by dimension name
+func (r JSONComparisonRow) MarshalJSON() ([]byte, error) {
+	obj := dimensionFields(r.Dimensions, r.Keys)
+	obj = append(obj,
+		jsonField{"current_clicks", r.CurrentClicks},
+		jsonField{"previous_clicks", r.PreviousClicks},
+		jsonField{"clicks_delta", r.ClicksDelta},
+		jsonField{"clicks_percent", r.ClicksPercent},
+		jsonField{"current_impressions", r.CurrentImpressions},
+		jsonField{"previous_impressions", r.PreviousImpressions},
+		jsonField{"impressions_delta", r.ImpressionsDelta},
+		jsonField{"impressions_percent", r.ImpressionsPercent},
+		jsonField{"current_position", r.CurrentPosition},
+		jsonField{"previous_position", r.PreviousPosition},
+		jsonField{"position_delta", r.PositionDelta},
+	)
+	return obj.MarshalJSON()
 }
 
 // PrintComparisonJSON prints comparison results as JSON
-func PrintComparisonJSON(currentPeriod, previousPeriod Period, rows []ComparisonRow) error {
+func PrintComparisonJSON(searchType string, dimensions []string, currentPeriod, previousPeriod Period, rows []ComparisonRow) error {
 	output := JSONComparisonResult{
+		SearchType:     searchType,
+		Dimensions:     dimensions,
 		CurrentPeriod:  currentPeriod,
 		PreviousPeriod: previousPeriod,
 		Rows:           make([]JSONComparisonRow, len(rows)),
@@ -94,7 +137,8 @@ func PrintComparisonJSON(currentPeriod, previousPeriod Period, rows []Comparison
 
 	for i, row := range rows {
 		output.Rows[i] = JSONComparisonRow{
-			Query:               row.Query,
+			Dimensions:          dimensions,
+			Keys:                row.Keys,
 			CurrentClicks:       row.CurrentClicks,
 			PreviousClicks:      row.PreviousClicks,
 			ClicksDelta:         row.ClicksDelta,
@@ -114,9 +158,12 @@ func PrintComparisonJSON(currentPeriod, previousPeriod Period, rows []Comparison
 
 // JSONDropsResult represents drops results in JSON format
 type JSONDropsResult struct {
-	Threshold float64        `json:"threshold"`
-	Count     int            `json:"count"`
-	Rows      []JSONDropsRow `json:"rows"`
+	SearchType     string         `json:"search_type"`
+	CurrentPeriod  Period         `json:"current_period"`
+	PreviousPeriod Period         `json:"previous_period"`
+	Threshold      float64        `json:"threshold"`
+	Count          int            `json:"count"`
+	Rows           []JSONDropsRow `json:"rows"`
 }
 
 // JSONDropsRow represents a drops row in JSON format
@@ -131,11 +178,14 @@ type JSONDropsRow struct {
 }
 
 // PrintDropsJSON prints drops results as JSON
-func PrintDropsJSON(threshold float64, rows []DropsRow) error {
+func PrintDropsJSON(searchType string, currentPeriod, previousPeriod Period, threshold float64, rows []DropsRow) error {
 	output := JSONDropsResult{
-		Threshold: threshold,
-		Count:     len(rows),
-		Rows:      make([]JSONDropsRow, len(rows)),
+		SearchType:     searchType,
+		CurrentPeriod:  currentPeriod,
+		PreviousPeriod: previousPeriod,
+		Threshold:      threshold,
+		Count:          len(rows),
+		Rows:           make([]JSONDropsRow, len(rows)),
 	}
 
 	for i, row := range rows {
@@ -153,6 +203,78 @@ func PrintDropsJSON(threshold float64, rows []DropsRow) error {
 	return printJSON(output)
 }
 
+// JSONRecords represents arbitrary rows in JSON format
+type JSONRecords struct {
+	Columns []string        `json:"columns"`
+	Count   int             `json:"count"`
+	Rows    []orderedObject `json:"rows"`
+}
+
+// PrintRecordsJSON prints arbitrary rows as JSON objects keyed by column name
+func PrintRecordsJSON(columns []string, values [][]interface{}) error {
+	output := JSONRecords{
+		Columns: columns,
+		Count:   len(values),
+		Rows:    make([]orderedObject, len(values)),
+	}
+
+	for i, row := range values {
+		obj := make(orderedObject, len(columns))
+		for j, col := range columns {
+			obj[j] = jsonField{col, row[j]}
+		}
+		output.Rows[i] = obj
+	}
+
+	return printJSON(output)
+}
+
+// jsonField is a key/value pair in an orderedObject
+type jsonField struct {
+	Key   string
+	Value interface{}
+}
+
+// orderedObject is a JSON object that keeps its keys in insertion order
+type orderedObject []jsonField
+
+// MarshalJSON encodes the fields in order
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(f.Key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// dimensionFields returns one field per dimension with its value
+func dimensionFields(dimensions, keys []string) orderedObject {
+	obj := make(orderedObject, 0, len(dimensions))
+	for i, dim := range dimensions {
+		value := ""
+		if i < len(keys) {
+			value = keys[i]
+		}
+		obj = append(obj, jsonField{dim, value})
+	}
+	return obj
+}
+
 func printJSON(v interface{}) error {
 	encoder := json.NewEncoder(os.Stdout)
 	encoder.SetIndent("", "  ")
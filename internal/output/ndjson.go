@@ -0,0 +1,45 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/sivori/gsc-cli/internal/api"
+)
+
+// WriteQueryRowsNDJSON streams rows as newline-delimited JSON, one object
+// per row with the same fields as --json, and returns how many were written
+func WriteQueryRowsNDJSON(w io.Writer, dimensions []string, rows iter.Seq2[api.QueryRow, error]) (int, error) {
+	buf := bufio.NewWriter(w)
+	defer buf.Flush()
+
+	encoder := json.NewEncoder(buf)
+	keys := make([]string, len(dimensions))
+
+	written := 0
+	for row, err := range rows {
+		if err != nil {
+			return written, err
+		}
+
+		for i, dim := range dimensions {
+			keys[i] = row.Dimension(dim)
+		}
+		if err := encoder.Encode(JSONQueryRow{
+			Dimensions:  dimensions,
+			Keys:        keys,
+			Clicks:      row.Clicks,
+			Impressions: row.Impressions,
+			CTR:         row.CTR,
+			Position:    row.Position,
+		}); err != nil {
+			return written, fmt.Errorf("could not write row: %w", err)
+		}
+		written++
+	}
+
+	return written, nil
+}
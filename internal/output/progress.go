@@ -0,0 +1,38 @@
+package output
+
+import (
+	"fmt"
+	"os"
+)
+
+// Progress prints a single updating status line to stderr.
+// Nothing is printed when stderr is not a terminal.
+type Progress struct {
+	label   string
+	enabled bool
+}
+
+// NewProgress creates a progress indicator with the given label
+func NewProgress(label string) *Progress {
+	enabled := false
+	if info, err := os.Stderr.Stat(); err == nil {
+		enabled = info.Mode()&os.ModeCharDevice != 0
+	}
+	return &Progress{label: label, enabled: enabled}
+}
+
+// Update shows the number of rows fetched so far
+func (p *Progress) Update(rows int) {
+	if !p.enabled {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\r%s %s rows...", p.label, FormatCount(rows))
+}
+
+// Done clears the progress line
+func (p *Progress) Done() {
+	if !p.enabled {
+		return
+	}
+	fmt.Fprint(os.Stderr, "\r\033[K")
+}
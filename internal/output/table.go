@@ -88,6 +88,18 @@ func FormatNumber(n float64) string {
 	return fmt.Sprintf("%.0f", n)
 }
 
+// FormatCount formats an integer count with thousands separators
+func FormatCount(n int) string {
+	if n < 0 {
+		return "-" + FormatCount(-n)
+	}
+	s := fmt.Sprintf("%d", n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
 // FormatCTR formats CTR as a percentage
 func FormatCTR(ctr float64) string {
 	return fmt.Sprintf("%.1f%%", ctr*100)
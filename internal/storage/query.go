@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/sivori/gsc-cli/internal/api"
+)
+
+var _ api.Querier = (*Store)(nil)
+
+// dimensionColumns maps API dimensions to warehouse columns
+var dimensionColumns = map[string]string{
+	"query":   "query",
+	"page":    "page",
+	"country": "country",
+	"device":  "device",
+	"date":    "date",
+}
+
+// Query answers a Search Analytics query from the warehouse. Metrics are
+// aggregated like the API does: clicks and impressions are summed, CTR is
+// recomputed from the sums, and position is weighted by impressions.
+func (s *Store) Query(ctx context.Context, req api.QueryRequest) (*api.QueryResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	if req.RowLimit == 0 {
+		req.RowLimit = 1000
+	}
+	return s.query(ctx, req)
+}
+
+// QueryAll answers a query from the warehouse without a row limit
+func (s *Store) QueryAll(ctx context.Context, req api.QueryRequest) (*api.QueryResult, error) {
+	return s.QueryAllWithProgress(ctx, req, nil)
+}
+
+// QueryAllWithProgress answers a query from the warehouse without a row limit
+func (s *Store) QueryAllWithProgress(ctx context.Context, req api.QueryRequest, progress api.ProgressFunc) (*api.QueryResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	req.RowLimit, req.StartRow = 0, 0
+	result, err := s.query(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if progress != nil {
+		progress(len(result.Rows))
+	}
+	return result, nil
+}
+
+// ProbeFreshness returns the last synced day. Only final data is synced,
+// so the latest day is never partial.
+func (s *Store) ProbeFreshness(ctx context.Context, searchType string, includeFresh bool) (*api.Freshness, error) {
+	searchType, err := api.NormalizeSearchType(searchType)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest sql.NullString
+	if err := s.db.QueryRowContext(ctx, `SELECT MAX(date) FROM sync_days WHERE search_type = ?`, searchType).Scan(&latest); err != nil {
+		return nil, fmt.Errorf("could not read sync state: %w", err)
+	}
+	if !latest.Valid {
+		return nil, fmt.Errorf("no local %s data for this site - run 'gsc sync' first", searchType)
+	}
+
+	return &api.Freshness{LatestDate: latest.String}, nil
+}
+
+func (s *Store) query(ctx context.Context, req api.QueryRequest) (*api.QueryResult, error) {
+	result := &api.QueryResult{
+		StartDate:  req.StartDate,
+		EndDate:    req.EndDate,
+		SearchType: req.SearchType,
+	}
+
+	err := s.eachRow(ctx, req, func(row api.QueryRow) bool {
+		result.Rows = append(result.Rows, row)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.TotalRows = len(result.Rows)
+	return result, nil
+}
+
+// Rows streams the rows of a query straight from the database cursor. A
+// positive RowLimit caps the number of rows.
+func (s *Store) Rows(ctx context.Context, req api.QueryRequest, progress api.ProgressFunc) iter.Seq2[api.QueryRow, error] {
+	return func(yield func(api.QueryRow, error) bool) {
+		if err := req.Validate(); err != nil {
+			yield(api.QueryRow{}, err)
+			return
+		}
+
+		fetched := 0
+		stopped := false
+		err := s.eachRow(ctx, req, func(row api.QueryRow) bool {
+			fetched++
+			if progress != nil && fetched%progressInterval == 0 {
+				progress(fetched)
+			}
+			stopped = !yield(row, nil)
+			return !stopped
+		})
+		if stopped {
+			return
+		}
+		if err != nil {
+			yield(api.QueryRow{}, err)
+			return
+		}
+		if progress != nil {
+			progress(fetched)
+		}
+	}
+}
+
+// progressInterval is how many rows Rows streams between progress reports
+const progressInterval = 25000
+
+// eachRow runs a query and calls fn with each row until it returns false
+func (s *Store) eachRow(ctx context.Context, req api.QueryRequest, fn func(api.QueryRow) bool) error {
+	dimensions := req.Dimensions
+	if len(dimensions) == 0 {
+		dimensions = []string{"query"}
+	}
+
+	var columns []string
+	for _, dim := range dimensions {
+		col, ok := dimensionColumns[dim]
+		if !ok {
+			return fmt.Errorf("invalid dimension: %s", dim)
+		}
+		columns = append(columns, col)
+	}
+
+	where := []string{"search_type = ?", "date >= ?", "date <= ?"}
+	args := []interface{}{req.SearchType, req.StartDate, req.EndDate}
+	for _, f := range req.Filters {
+		clause, arg, err := filterClause(f)
+		if err != nil {
+			return err
+		}
+		where = append(where, clause)
+		args = append(args, arg)
+	}
+
+	q := fmt.Sprintf(`SELECT %s,
+		SUM(clicks) AS clicks,
+		SUM(impressions) AS impressions,
+		CASE WHEN SUM(impressions) > 0 THEN SUM(clicks) / SUM(impressions) ELSE 0 END AS ctr,
+		CASE WHEN SUM(impressions) > 0 THEN SUM(position * impressions) / SUM(impressions) ELSE 0 END AS position
+		FROM search_analytics
+		WHERE %s
+		GROUP BY %s
+		ORDER BY clicks DESC, impressions DESC`,
+		strings.Join(columns, ", "), strings.Join(where, " AND "), strings.Join(columns, ", "))
+	if req.RowLimit > 0 {
+		q += fmt.Sprintf(" LIMIT %d OFFSET %d", req.RowLimit, req.StartRow)
+	}
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("local query failed: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]string, len(dimensions))
+	for rows.Next() {
+		var qr api.QueryRow
+		dest := make([]interface{}, 0, len(dimensions)+4)
+		for i := range keys {
+			dest = append(dest, &keys[i])
+		}
+		dest = append(dest, &qr.Clicks, &qr.Impressions, &qr.CTR, &qr.Position)
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("local query failed: %w", err)
+		}
+
+		for i, dim := range dimensions {
+			switch dim {
+			case "query":
+				qr.Query = keys[i]
+			case "page":
+				qr.Page = keys[i]
+			case "country":
+				qr.Country = keys[i]
+			case "device":
+				qr.Device = keys[i]
+			case "date":
+				qr.Date = keys[i]
+			}
+		}
+
+		if !fn(qr) {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("local query failed: %w", err)
+	}
+
+	return nil
+}
+
+// filterClause translates an API filter into a SQL condition
+func filterClause(f api.Filter) (string, interface{}, error) {
+	col, ok := dimensionColumns[f.Dimension]
+	if !ok || col == "date" {
+		return "", nil, fmt.Errorf("invalid filter dimension: %s", f.Dimension)
+	}
+
+	// The API compares country and device case-insensitively, but query and
+	// page exactly
+	collate := ""
+	if col == "country" || col == "device" {
+		collate = " COLLATE NOCASE"
+	}
+
+	switch f.Operator {
+	case api.OperatorEquals:
+		return col + " = ?" + collate, f.Expression, nil
+	case api.OperatorNotEquals:
+		return col + " <> ?" + collate, f.Expression, nil
+	case api.OperatorContains:
+		return col + ` LIKE ? ESCAPE '\'`, "%" + escapeLike(f.Expression) + "%", nil
+	case api.OperatorNotContains:
+		return col + ` NOT LIKE ? ESCAPE '\'`, "%" + escapeLike(f.Expression) + "%", nil
+	case api.OperatorIncludingRegex:
+		return col + " REGEXP ?", f.Expression, nil
+	case api.OperatorExcludingRegex:
+		return col + " NOT REGEXP ?", f.Expression, nil
+	}
+	return "", nil, fmt.Errorf("invalid filter operator: %s", f.Operator)
+}
+
+// escapeLike escapes LIKE wildcards so the expression matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
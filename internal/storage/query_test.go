@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sivori/gsc-cli/internal/api"
+)
+
+func TestQueryEqualsFilterCase(t *testing.T) {
+	store := openTestStore(t)
+	rows := []api.QueryRow{
+		{Query: "Shoes", Page: "https://example.com/Shoes", Country: "usa", Device: "MOBILE", Clicks: 1, Impressions: 10},
+		{Query: "shoes", Page: "https://example.com/shoes", Country: "usa", Device: "MOBILE", Clicks: 2, Impressions: 20},
+	}
+	if err := store.ReplaceDay("web", "2026-09-01", "", rows); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dimension, operator, expression string
+		want                            int
+	}{
+		{"query", api.OperatorEquals, "shoes", 1},
+		{"query", api.OperatorNotEquals, "shoes", 1},
+		{"page", api.OperatorEquals, "https://example.com/Shoes", 1},
+		{"country", api.OperatorEquals, "USA", 2},
+		{"device", api.OperatorEquals, "mobile", 2},
+		{"device", api.OperatorNotEquals, "mobile", 0},
+		{"page", api.OperatorIncludingRegex, `/[A-Z]\w+$`, 1},
+		{"query", api.OperatorExcludingRegex, "^s", 1},
+	}
+
+	for _, tt := range tests {
+		result, err := store.Query(context.Background(), api.QueryRequest{
+			StartDate:  "2026-09-01",
+			EndDate:    "2026-09-01",
+			Dimensions: []string{"query", "page"},
+			Filters:    []api.Filter{{Dimension: tt.dimension, Operator: tt.operator, Expression: tt.expression}},
+			SearchType: "web",
+		})
+		if err != nil {
+			t.Fatalf("%s %s %s: %v", tt.dimension, tt.operator, tt.expression, err)
+		}
+		if len(result.Rows) != tt.want {
+			t.Errorf("%s %s %s matched %d rows, want %d", tt.dimension, tt.operator, tt.expression, len(result.Rows), tt.want)
+		}
+	}
+}
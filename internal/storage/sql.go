@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// OpenReadOnly opens an existing local database for a site. Writes through
+// the returned store fail.
+func OpenReadOnly(siteURL string) (*Store, error) {
+	path, err := Path(siteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no local data for %s - run 'gsc sync' first", siteURL)
+		}
+		return nil, fmt.Errorf("could not open database: %w", err)
+	}
+
+	// Bring the schema up to date before reopening read-only
+	store, err := Open(siteURL)
+	if err != nil {
+		return nil, err
+	}
+	store.Close()
+
+	db, err := sql.Open(driverName, "file:"+path+"?mode=ro&_query_only=true&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("could not open database: %w", err)
+	}
+
+	return &Store{db: db, path: path}, nil
+}
+
+// Rows is the result of an ad-hoc SQL statement
+type Rows struct {
+	Columns []string
+	Values  [][]interface{}
+}
+
+// SQL runs an ad-hoc statement and returns every row
+func (s *Store) SQL(ctx context.Context, statement string) (*Rows, error) {
+	rows, err := s.db.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	result := &Rows{Columns: columns}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("query failed: %w", err)
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		result.Values = append(result.Values, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// Table describes a table or view in the local database
+type Table struct {
+	Name    string
+	Type    string // "table" or "view"
+	Columns []Column
+}
+
+// Column describes a column of a table or view
+type Column struct {
+	Name string
+	Type string
+}
+
+// Schema describes the tables and views in the local database
+func (s *Store) Schema() ([]Table, error) {
+	rows, err := s.db.Query(`SELECT name, type FROM sqlite_master
+		WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%'
+		ORDER BY type, name`)
+	if err != nil {
+		return nil, fmt.Errorf("could not read schema: %w", err)
+	}
+
+	var tables []Table
+	for rows.Next() {
+		var t Table
+		if err := rows.Scan(&t.Name, &t.Type); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("could not read schema: %w", err)
+		}
+		tables = append(tables, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read schema: %w", err)
+	}
+
+	for i := range tables {
+		cols, err := s.db.Query(`SELECT name, type FROM pragma_table_info(?) ORDER BY cid`, tables[i].Name)
+		if err != nil {
+			return nil, fmt.Errorf("could not read columns of %s: %w", tables[i].Name, err)
+		}
+		for cols.Next() {
+			var c Column
+			if err := cols.Scan(&c.Name, &c.Type); err != nil {
+				cols.Close()
+				return nil, fmt.Errorf("could not read columns of %s: %w", tables[i].Name, err)
+			}
+			tables[i].Columns = append(tables[i].Columns, c)
+		}
+		cols.Close()
+	}
+
+	return tables, nil
+}
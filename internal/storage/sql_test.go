@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOpenReadOnlyRejectsWrites(t *testing.T) {
+	store := openTestStore(t)
+	if err := store.ReplaceDay("web", "2026-09-01", "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ro, err := OpenReadOnly("sc-domain:example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+
+	rows, err := ro.SQL(context.Background(), "SELECT COUNT(*) FROM sync_days WHERE 'abc' REGEXP 'b+'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := rows.Values[0][0].(int64); n != 1 {
+		t.Errorf("count = %v, want 1", rows.Values[0][0])
+	}
+
+	if _, err := ro.SQL(context.Background(), "DELETE FROM sync_days"); err == nil {
+		t.Error("DELETE on a read-only store succeeded")
+	}
+}
@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/sivori/gsc-cli/internal/api"
+	"github.com/sivori/gsc-cli/internal/config"
+
+	"modernc.org/sqlite"
+)
+
+// driverName is the pure-Go SQLite driver, so gsc builds without cgo. Its
+// connections get a REGEXP function using Go's RE2 syntax, matching the
+// Search Console API's regex filters.
+const driverName = "sqlite"
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("regexp", 2, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		pattern, ok := args[0].(string)
+		if !ok {
+			return nil, nil
+		}
+		value, ok := args[1].(string)
+		if !ok {
+			return nil, nil
+		}
+		return regexpMatch(pattern, value)
+	})
+}
+
+var (
+	regexpCacheMu sync.Mutex
+	regexpCache   = make(map[string]*regexp.Regexp)
+)
+
+// regexpMatch implements "value REGEXP pattern"
+func regexpMatch(pattern, value string) (bool, error) {
+	regexpCacheMu.Lock()
+	re, ok := regexpCache[pattern]
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			regexpCacheMu.Unlock()
+			return false, err
+		}
+		regexpCache[pattern] = re
+	}
+	regexpCacheMu.Unlock()
+	return re.MatchString(value), nil
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS search_analytics (
+	date        TEXT NOT NULL,
+	search_type TEXT NOT NULL,
+	query       TEXT NOT NULL,
+	page        TEXT NOT NULL,
+	country     TEXT NOT NULL,
+	device      TEXT NOT NULL,
+	clicks      REAL NOT NULL,
+	impressions REAL NOT NULL,
+	ctr         REAL NOT NULL,
+	position    REAL NOT NULL,
+	PRIMARY KEY (search_type, date, query, page, country, device)
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_analytics_date ON search_analytics (date);
+
+CREATE TABLE IF NOT EXISTS sync_days (
+	search_type TEXT NOT NULL,
+	date        TEXT NOT NULL,
+	rows        INTEGER NOT NULL,
+	synced_at   TEXT NOT NULL,
+	PRIMARY KEY (search_type, date)
+);
+
+CREATE TABLE IF NOT EXISTS sync_runs (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	search_type TEXT NOT NULL,
+	start_date  TEXT NOT NULL,
+	end_date    TEXT NOT NULL,
+	started_at  TEXT NOT NULL,
+	finished_at TEXT,
+	days        INTEGER NOT NULL DEFAULT 0,
+	rows        INTEGER NOT NULL DEFAULT 0,
+	status      TEXT NOT NULL,
+	error       TEXT
+);
+
+CREATE VIEW IF NOT EXISTS daily_queries AS
+SELECT search_type, date, query,
+	SUM(clicks) AS clicks,
+	SUM(impressions) AS impressions,
+	SUM(clicks) / NULLIF(SUM(impressions), 0) AS ctr,
+	SUM(position * impressions) / NULLIF(SUM(impressions), 0) AS position
+FROM search_analytics
+GROUP BY search_type, date, query;
+
+CREATE VIEW IF NOT EXISTS daily_pages AS
+SELECT search_type, date, page,
+	SUM(clicks) AS clicks,
+	SUM(impressions) AS impressions,
+	SUM(clicks) / NULLIF(SUM(impressions), 0) AS ctr,
+	SUM(position * impressions) / NULLIF(SUM(impressions), 0) AS position
+FROM search_analytics
+GROUP BY search_type, date, page;
+`
+
+// Store is a local SQLite warehouse of Search Analytics data for one site
+type Store struct {
+	db   *sql.DB
+	path string
+}
+
+// unsafePathChars matches characters that cannot appear in a database file name
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9.\-]+`)
+
+// Path returns the database file path for a site
+func Path(siteURL string) (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	name := unsafePathChars.ReplaceAllString(siteURL, "_")
+	return filepath.Join(dir, name+".db"), nil
+}
+
+// Open opens (creating if needed) the local database for a site
+func Open(siteURL string) (*Store, error) {
+	path, err := Path(siteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("could not create data directory: %w", err)
+	}
+
+	db, err := sql.Open(driverName, path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("could not open database: %w", err)
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not initialize database: %w", err)
+	}
+
+	return &Store{db: db, path: path}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Path returns the database file path
+func (s *Store) Path() string {
+	return s.path
+}
+
+// SyncedDays returns the set of days already synced for a search type
+func (s *Store) SyncedDays(searchType string) (map[string]bool, error) {
+	rows, err := s.db.Query(`SELECT date FROM sync_days WHERE search_type = ?`, searchType)
+	if err != nil {
+		return nil, fmt.Errorf("could not read sync state: %w", err)
+	}
+	defer rows.Close()
+
+	days := make(map[string]bool)
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, fmt.Errorf("could not read sync state: %w", err)
+		}
+		days[date] = true
+	}
+	return days, rows.Err()
+}
+
+// ErrIncompleteDay is returned by ReplaceDay for a day without final data yet
+var ErrIncompleteDay = errors.New("no final data yet")
+
+// ReplaceDay stores the rows for one day, replacing anything synced before.
+// An empty day on or after firstIncomplete is not recorded as synced, as its
+// data may still arrive; ErrIncompleteDay is returned instead.
+func (s *Store) ReplaceDay(searchType, date, firstIncomplete string, rows []api.QueryRow) error {
+	if len(rows) == 0 && firstIncomplete != "" && date >= firstIncomplete {
+		return fmt.Errorf("%s: %w", date, ErrIncompleteDay)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM search_analytics WHERE search_type = ? AND date = ?`, searchType, date); err != nil {
+		return fmt.Errorf("could not clear %s: %w", date, err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO search_analytics
+		(date, search_type, query, page, country, device, clicks, impressions, ctr, position)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("could not prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.Exec(date, searchType, row.Query, row.Page, row.Country, row.Device,
+			row.Clicks, row.Impressions, row.CTR, row.Position); err != nil {
+			return fmt.Errorf("could not insert row for %s: %w", date, err)
+		}
+	}
+
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO sync_days (search_type, date, rows, synced_at) VALUES (?, ?, ?, ?)`,
+		searchType, date, len(rows), now()); err != nil {
+		return fmt.Errorf("could not record sync state: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// StartRun records the start of a sync run and returns its ID
+func (s *Store) StartRun(searchType, startDate, endDate string) (int64, error) {
+	res, err := s.db.Exec(`INSERT INTO sync_runs (search_type, start_date, end_date, started_at, status)
+		VALUES (?, ?, ?, ?, 'running')`, searchType, startDate, endDate, now())
+	if err != nil {
+		return 0, fmt.Errorf("could not record sync run: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// FinishRun records the outcome of a sync run
+func (s *Store) FinishRun(id int64, days, rows int, runErr error) error {
+	status, errMsg := "ok", ""
+	if runErr != nil {
+		status, errMsg = "failed", runErr.Error()
+	}
+	_, err := s.db.Exec(`UPDATE sync_runs SET finished_at = ?, days = ?, rows = ?, status = ?, error = ? WHERE id = ?`,
+		now(), days, rows, status, errMsg, id)
+	if err != nil {
+		return fmt.Errorf("could not record sync run: %w", err)
+	}
+	return nil
+}
+
+// Status summarizes what has been synced for a search type
+type Status struct {
+	Days        int
+	Rows        int64
+	FirstDate   string
+	LastDate    string
+	LastRunAt   string
+	LastRunInfo string
+}
+
+// Status returns a summary of the synced data for a search type
+func (s *Store) Status(searchType string) (*Status, error) {
+	st := &Status{}
+	var first, last sql.NullString
+	var rows sql.NullInt64
+	err := s.db.QueryRow(`SELECT COUNT(*), SUM(rows), MIN(date), MAX(date) FROM sync_days WHERE search_type = ?`,
+		searchType).Scan(&st.Days, &rows, &first, &last)
+	if err != nil {
+		return nil, fmt.Errorf("could not read sync state: %w", err)
+	}
+	st.Rows, st.FirstDate, st.LastDate = rows.Int64, first.String, last.String
+
+	var finished, status, errMsg sql.NullString
+	err = s.db.QueryRow(`SELECT finished_at, status, error FROM sync_runs WHERE search_type = ? ORDER BY id DESC LIMIT 1`,
+		searchType).Scan(&finished, &status, &errMsg)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("could not read sync runs: %w", err)
+	}
+	st.LastRunAt = finished.String
+	st.LastRunInfo = status.String
+	if errMsg.String != "" {
+		st.LastRunInfo += ": " + errMsg.String
+	}
+
+	return st, nil
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
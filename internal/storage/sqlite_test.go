@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sivori/gsc-cli/internal/api"
+)
+
+// openTestStore opens a store for a site under a temporary config directory
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	store, err := Open("sc-domain:example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestReplaceDaySkipsEmptyIncompleteDays(t *testing.T) {
+	store := openTestStore(t)
+	row := []api.QueryRow{{Query: "q", Page: "https://example.com/", Country: "usa", Device: "DESKTOP", Clicks: 1, Impressions: 10}}
+
+	tests := []struct {
+		date     string
+		rows     []api.QueryRow
+		wantErr  bool
+		recorded bool
+	}{
+		{"2026-09-28", row, false, true},
+		{"2026-09-29", nil, false, true}, // empty but complete
+		{"2026-09-30", nil, true, false}, // the first incomplete day
+		{"2026-10-01", nil, true, false},
+		{"2026-10-02", row, false, true},
+	}
+
+	for _, tt := range tests {
+		err := store.ReplaceDay("web", tt.date, "2026-09-30", tt.rows)
+		if tt.wantErr != errors.Is(err, ErrIncompleteDay) {
+			t.Errorf("ReplaceDay(%s) error = %v, want ErrIncompleteDay: %v", tt.date, err, tt.wantErr)
+		} else if !tt.wantErr && err != nil {
+			t.Errorf("ReplaceDay(%s) error = %v", tt.date, err)
+		}
+	}
+
+	synced, err := store.SyncedDays("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if synced[tt.date] != tt.recorded {
+			t.Errorf("day %s recorded = %v, want %v", tt.date, synced[tt.date], tt.recorded)
+		}
+	}
+}